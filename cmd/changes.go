@@ -4,71 +4,354 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 
-	"github.com/fatih/color"
+	"github.com/pulumi/kubespy/diff"
+	"github.com/pulumi/kubespy/k8sconfig"
+	"github.com/pulumi/kubespy/print"
+	"github.com/pulumi/kubespy/trace"
 	"github.com/pulumi/kubespy/watch"
 	"github.com/spf13/cobra"
-	"github.com/yudai/gojsondiff"
-	"github.com/yudai/gojsondiff/formatter"
+	"github.com/spf13/pflag"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
 	apiwatch "k8s.io/apimachinery/pkg/watch"
 )
 
+var (
+	diffFormat       string
+	ignorePaths      []string
+	noDefaultIgnores bool
+	attribute        bool
+	changesPath      string
+
+	watchAll      bool
+	labelSelector string
+	namespaceFlag string
+	allNamespaces bool
+)
+
 func init() {
+	addChangesFlags(changesCmd.Flags(), &changesPath, ".")
 	rootCmd.AddCommand(changesCmd)
 }
 
+// addChangesFlags adds the flags that choose which objects to watch, and control how their changes
+// are diffed, which `changes` and `status` share. Each has its own `--path`, with its own default.
+func addChangesFlags(flags *pflag.FlagSet, path *string, defaultPath string) {
+	addDiffFlags(flags, path, defaultPath)
+	flags.BoolVar(&watchAll, "all", false,
+		"Watch every object of the kind, as when no name is given")
+	flags.StringVarP(&labelSelector, "selector", "l", "",
+		"Watch every object of the kind matching this label selector, e.g., 'app=nginx'")
+	flags.StringVarP(&namespaceFlag, "namespace", "n", "",
+		"Namespace to watch, if it isn't given as part of the name. Defaults to the current context's")
+	flags.BoolVarP(&allNamespaces, "all-namespaces", "A", false,
+		"Watch objects in every namespace, when no name is given")
+	addEventsFlag(flags)
+}
+
+// addDiffFlags adds the flags that control how changes are diffed.
+func addDiffFlags(flags *pflag.FlagSet, path *string, defaultPath string) {
+	flags.StringVar(path, "path", defaultPath,
+		"Only show changes to the part of the object at this path, e.g., '.spec.template' or '.data'")
+	flags.StringVar(&diffFormat, "diff-format", string(diff.KeyedYAML),
+		"Format of each diff. One of: keyed (the path of each changed field, with its old and new "+
+			"YAML), ascii (an annotated JSON diff), jsonpatch (RFC 6902), mergepatch (RFC 7386), "+
+			"unified-yaml, delta-json")
+	flags.StringSliceVar(&ignorePaths, "ignore", []string{},
+		"Fields to leave out of diffs, e.g., 'metadata.labels,status.conditions[*].lastTransitionTime'. "+
+			"Changes only to ignored fields are not shown at all")
+	flags.BoolVar(&noDefaultIgnores, "no-default-ignores", false,
+		"Show changes to fields that are ignored by default: "+strings.Join(diff.DefaultIgnores, ", "))
+	flags.BoolVar(&attribute, "attribute", false,
+		"Show which field manager (e.g., kubectl, a controller, or an HPA) made each change, "+
+			"according to '.metadata.managedFields'")
+}
+
+// parseTargetID is parseObjID, but a name without a namespace is in the namespace given by
+// `--namespace`, if any.
+func parseTargetID(objID string) (namespace, name string, err error) {
+	namespace, name, err = parseObjID(objID)
+	if err == nil && namespaceFlag != "" && !strings.Contains(objID, "/") {
+		namespace = namespaceFlag
+	}
+	return namespace, name, err
+}
+
+// targetNamespace is the namespace whose objects to watch when no name is given: the one given with
+// `--namespace`, the current context's, or, with `--all-namespaces`, every namespace (i.e., "").
+func targetNamespace() (string, error) {
+	if allNamespaces {
+		return "", nil
+	} else if namespaceFlag != "" {
+		return namespaceFlag, nil
+	}
+	namespace, _, err := k8sconfig.New().Namespace()
+	return namespace, err
+}
+
+// changesTarget decides which objects `changes` and `status` watch from their arguments: the object
+// named by the third argument, or, if there is none, every object of the kind (matching
+// `--selector`, if given). `many` is true in the latter case, and `desc` describes the objects for
+// the banner.
+func changesTarget(args []string) (opts watch.Opts, many bool, desc string, err error) {
+	if len(args) == 3 {
+		if watchAll || labelSelector != "" || allNamespaces {
+			return watch.Opts{}, false, "", fmt.Errorf(
+				"Can't watch the object '%s' and also --all, --selector, or --all-namespaces", args[2])
+		}
+		namespace, name, err := parseTargetID(args[2])
+		if err != nil {
+			return watch.Opts{}, false, "", err
+		}
+		return watch.ThisObject(namespace, name), false,
+			fmt.Sprintf("%s %s %s/%s", args[0], args[1], namespace, name), nil
+	}
+
+	namespace, err := targetNamespace()
+	if err != nil {
+		return watch.Opts{}, false, "", err
+	}
+
+	desc = fmt.Sprintf("every %s %s", args[0], args[1])
+	if labelSelector != "" {
+		desc += fmt.Sprintf(" labeled '%s'", labelSelector)
+	}
+	if namespace == "" {
+		desc += " in every namespace"
+	} else {
+		desc += " in namespace " + namespace
+	}
+
+	if labelSelector != "" {
+		return watch.ObjectsLabeled(namespace, labelSelector), true, desc, nil
+	}
+	return watch.All(namespace), true, desc, nil
+}
+
 var changesCmd = &cobra.Command{
-	Use:   "changes <apiVersion> <kind> [<namespace>/]<name>",
+	Use:   "changes <apiVersion> <kind> [[<namespace>/]<name>]",
 	Short: "Displays changes made to a Kubernetes resource in real time. Emitted as JSON diffs",
-	Args:  cobra.ExactArgs(3),
+	Long: `Displays changes made to a Kubernetes resource in real time. Emitted as JSON diffs.
+If no name is given, displays changes to every object of the kind in the namespace (or those
+matching --selector), each headed by the object it was made to. With --events, the Kubernetes Events
+regarding the objects are shown as they happen, too.`,
+	Args: cobra.RangeArgs(2, 3),
 	Run: func(cmd *cobra.Command, args []string) {
-		namespace, name, err := parseObjID(args[2])
+		opts, many, desc, err := changesTarget(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		path, err := diff.ParsePath(changesPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(path) == 0 {
+			print.Banner(os.Stdout, "Watching for changes on %s", desc)
+		} else {
+			print.Banner(os.Stdout, "Watching for changes to %s of %s", path, desc)
+		}
+		watchChanges(args[0], args[1], opts, many, path)
+	},
+}
+
+// baseline is the last state of an object that its next change is diffed against.
+type baseline struct {
+	// o is the object without its ignored fields, and raw is the whole object.
+	o, raw map[string]interface{}
+}
+
+// watchChanges prints every change to the part at `path` of the objects `opts` selects, forever.
+// If there may be `many` such objects, each change is headed by the object it was made to.
+func watchChanges(apiVersion, kind string, opts watch.Opts, many bool, path diff.Path) {
+	printer := newChangePrinter(path, listSchema(apiVersion, kind), many)
+
+	events, err := watch.Forever(apiVersion, kind, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if withEvents {
+		if events, err = followKubeEvents(opts.Namespace(), events); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	for {
+		select {
+		case e := <-events:
+			printer.observe(e, true)
+		}
+	}
+}
+
+// changePrinter prints the changes to the part at `path` of each object in a stream of watch
+// events, diffed against the object's own previous state, as configured by the flags of `changes`.
+type changePrinter struct {
+	format    diff.Format
+	ignores   []diff.Path
+	schema    diff.Schema
+	path      diff.Path
+	many      bool
+	baselines map[types.UID]baseline
+
+	// kubeEvents folds together the repetitions of each Kubernetes Event, which are printed as they
+	// happen rather than diffed.
+	kubeEvents *trace.EventLog
+
+	// note, if set, is printed above the next change, e.g., when it was recorded.
+	note string
+}
+
+// newChangePrinter creates a changePrinter. `schema` may be nil, in which case the elements of
+// well-known lists are matched up by their usual keys, as diff.StaticSchema knows them. If there may be `many` objects, each change is headed by the object it was made
+// to.
+func newChangePrinter(path diff.Path, schema diff.Schema, many bool) *changePrinter {
+	format, err := diff.ParseFormat(diffFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	specs := ignorePaths
+	if !noDefaultIgnores {
+		specs = append(append([]string{}, diff.DefaultIgnores...), ignorePaths...)
+	}
+	ignores, err := diff.ParsePaths(specs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if schema == nil {
+		schema = diff.StaticSchema
+	}
+
+	return &changePrinter{
+		format: format, ignores: ignores, schema: schema, path: path, many: many,
+		baselines: map[types.UID]baseline{}, kubeEvents: trace.NewEventLog(),
+	}
+}
+
+// observe makes the object's state after `e` the baseline for its next change. The change itself is
+// printed only if `show` is true (e.g., replays skip the changes before the time they seek to).
+// `printed` is false if nothing was printed, e.g., because only ignored fields changed.
+func (p *changePrinter) observe(e apiwatch.Event, show bool) (printed bool) {
+	obj := e.Object.(*unstructured.Unstructured)
+	if trace.IsEvent(obj) {
+		return p.observeKubeEvent(e, show)
+	}
+
+	raw := obj.Object
+	last, lastRaw := p.baselines[obj.GetUID()].o, p.baselines[obj.GetUID()].raw
+	o := diff.Without(raw, p.ignores)
+
+	switch {
+	case last == nil:
+		p.baselines[obj.GetUID()] = baseline{o: o, raw: raw}
+		if !show {
+			return false
+		}
+		p.heading(obj, "CREATED")
+
+		// Ignored fields are left out of diffs, but the object is shown as it was created.
+		ojson, err := json.MarshalIndent(p.path.Subtree(raw), "", "  ")
 		if err != nil {
 			log.Fatal(err)
 		}
+		print.NewObject(os.Stdout, string(ojson))
+		return true
+	case e.Type == apiwatch.Deleted:
+		delete(p.baselines, obj.GetUID())
+		if !show {
+			return false
+		}
+		p.heading(obj, string(e.Type))
+		return true
+	default:
+		p.baselines[obj.GetUID()] = baseline{o: o, raw: raw}
+		if !show {
+			return false
+		}
 
-		events, err := watch.Forever(args[0], args[1], watch.ThisObject(namespace, name))
+		// Suppress events that only change ignored fields, or fields outside `path`.
+		text, modified, err := p.diff(last, o)
 		if err != nil {
 			log.Fatal(err)
+		} else if !modified {
+			return false
 		}
 
-		fmt.Println(color.GreenString("Watching for changes on %s %s %s", args[0], args[1], args[2]))
-
-		heading := color.New(color.FgBlue, color.Bold)
-
-		var last *unstructured.Unstructured
-		for {
-			select {
-			case e := <-events:
-				o := e.Object.(*unstructured.Unstructured)
-				switch e.Type {
-				case apiwatch.Added:
-					heading.Println("CREATED")
-
-					ojson, err := json.MarshalIndent(o.Object, "", "  ")
-					if err != nil {
-						log.Fatal(err)
-					}
-					fmt.Println(color.GreenString(string(ojson)))
-				case apiwatch.Modified:
-					heading.Println(string(e.Type))
-
-					diff := gojsondiff.New().CompareObjects(last.Object, o.Object)
-					if diff.Modified() {
-						fcfg := formatter.AsciiFormatterConfig{Coloring: true}
-						formatter := formatter.NewAsciiFormatter(last.Object, fcfg)
-						text, err := formatter.Format(diff)
-						if err != nil {
-							log.Fatal(err)
-						}
-						fmt.Println(text)
-					}
-				case apiwatch.Deleted:
-					heading.Println(string(e.Type))
+		// An object that is `ADDED` again (e.g., in a recording whose watches began afresh when
+		// they expired) has just changed.
+		p.heading(obj, string(apiwatch.Modified))
+		fmt.Println(text)
+		if attribute {
+			attributions := []diff.Attribution{}
+			for _, a := range diff.Attribute(lastRaw, raw, p.ignores, p.schema) {
+				if a.Path.HasPrefix(p.path) {
+					attributions = append(attributions, a)
 				}
-				last = o
 			}
+			print.Attributions(os.Stdout, attributions)
 		}
-	},
+		return true
+	}
+}
+
+// diff renders the changes to the part at `path` of an object from `before` to `after`. The keyed
+// format lists them with list elements matched up by the keys in `schema`; the others show the
+// objects as they are.
+func (p *changePrinter) diff(before, after map[string]interface{}) (string, bool, error) {
+	if p.format == diff.KeyedYAML {
+		return print.KeyedDiff(before, after, p.schema, p.path)
+	}
+	return print.FormattedDiff(p.path.Subtree(before), p.path.Subtree(after), p.format)
+}
+
+// observeKubeEvent prints the Kubernetes Event in `e`, if it has not happened before, or has
+// happened again, and `show` is true.
+func (p *changePrinter) observeKubeEvent(e apiwatch.Event, show bool) (printed bool) {
+	// Events are deleted once they expire, which is not itself news.
+	if e.Type == apiwatch.Deleted {
+		return false
+	}
+	kubeEvent, news := p.kubeEvents.Observe(e.Object.(*unstructured.Unstructured))
+	if !news || !show {
+		return false
+	}
+	p.printNote()
+	print.KubeEvent(os.Stdout, kubeEvent)
+	return true
+}
+
+func (p *changePrinter) heading(obj *unstructured.Unstructured, h string) {
+	p.printNote()
+	if p.many {
+		print.ObjectHeading(os.Stdout, h, obj.GetKind(), obj.GetNamespace(), obj.GetName())
+	} else {
+		print.Heading(os.Stdout, h)
+	}
+}
+
+func (p *changePrinter) printNote() {
+	if p.note != "" {
+		print.Note(os.Stdout, p.note)
+		p.note = ""
+	}
+}
+
+// listSchema returns the schema that tells diffs of `apiVersion` `kind` how to match up list
+// elements, e.g., a Pod's containers by name, or nil if the API server doesn't publish one.
+func listSchema(apiVersion, kind string) diff.Schema {
+	doc, err := watch.OpenAPISchema()
+	if err != nil {
+		return nil
+	}
+	schema, err := diff.NewOpenAPISchema(doc, apiVersion, kind)
+	if err != nil {
+		return nil
+	}
+	return schema
 }
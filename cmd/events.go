@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"github.com/pulumi/kubespy/trace"
+	"github.com/pulumi/kubespy/watch"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	apiwatch "k8s.io/apimachinery/pkg/watch"
+)
+
+var withEvents bool
+
+// maxPendingEvents is how many Kubernetes Events are held back, waiting for the object they regard
+// to be observed, before the oldest are dropped.
+const maxPendingEvents = 100
+
+// addEventsFlag adds the flag that turns on watching Kubernetes Events, which `record`, `changes`,
+// `status`, and `trace` share.
+func addEventsFlag(flags *pflag.FlagSet) {
+	flags.BoolVar(&withEvents, "events", false,
+		"Also watch the Kubernetes Events (e.g., FailedScheduling, BackOff, or ScalingReplicaSet) "+
+			"regarding the objects, and every object they own")
+}
+
+// followKubeEvents forwards `objects`, the watch events of the objects being spied on, along with
+// those of the Kubernetes Events in `namespace` that regard them or their descendants, as
+// determined by watching every kind of object in `namespace` (as `tree` does). Every change to such
+// an Event is forwarded; it is up to the receiver to fold repetitions together (see
+// trace.EventLog).
+//
+// An Event may be observed before the object it regards, e.g., when a Pod is scheduled as soon as
+// it is created, so Events that regard no known object are held back until they do.
+func followKubeEvents(
+	namespace string, objects <-chan apiwatch.Event,
+) (<-chan apiwatch.Event, error) {
+	kubeEvents, err := watch.Events(namespace)
+	if err != nil {
+		return nil, err
+	}
+	owned, err := watch.AllNamespacedKinds(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan apiwatch.Event)
+	go func() {
+		family := trace.NewFamily()
+		pending := []apiwatch.Event{}
+
+		// forward forwards `e` if it regards a member of the family. It returns false if `e` regards
+		// no member of the family (yet).
+		forward := func(e apiwatch.Event) bool {
+			if !family.Regards(e.Object.(*unstructured.Unstructured)) {
+				return false
+			}
+			out <- e
+			return true
+		}
+		retry := func() {
+			held := []apiwatch.Event{}
+			for _, e := range pending {
+				if !forward(e) {
+					held = append(held, e)
+				}
+			}
+			pending = held
+		}
+
+		for {
+			select {
+			case e := <-objects:
+				o := e.Object.(*unstructured.Unstructured)
+				family.AddRoot(o)
+				if e.Type == apiwatch.Deleted {
+					family.Delete(o)
+				}
+				out <- e
+				retry()
+			case e := <-owned:
+				o := e.Object.(*unstructured.Unstructured)
+				family.Observe(o)
+				if e.Type == apiwatch.Deleted {
+					family.Delete(o)
+				}
+				retry()
+			case e := <-kubeEvents:
+				// Events are deleted once they expire, which is not itself news.
+				if e.Type == apiwatch.Deleted || forward(e) {
+					continue
+				}
+				pending = append(pending, e)
+				if len(pending) > maxPendingEvents {
+					pending = pending[len(pending)-maxPendingEvents:]
+				}
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pulumi/kubespy/export"
+	"github.com/pulumi/kubespy/print"
+	"github.com/pulumi/kubespy/recording"
+	"github.com/pulumi/kubespy/replay"
+	"github.com/pulumi/kubespy/trace"
+	"github.com/spf13/cobra"
+	k8sWatch "k8s.io/apimachinery/pkg/watch"
+)
+
+var (
+	exportFormat        string
+	exportView          string
+	exportOutput        string
+	exportSpeed         string
+	exportSeek          string
+	exportIdleTimeLimit time.Duration
+	exportWidth         int
+	exportHeight        int
+)
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormat, "format", "asciicast",
+		"Format to export the recording to. One of: asciicast (an asciinema v2 cast)")
+	exportCmd.Flags().StringVar(&exportView, "view", "trace",
+		"How to show the recording. One of: trace (as 'kubespy trace' would for Deployments and "+
+			"Services, and 'kubespy tree' would for anything else)")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "",
+		"File to write the export to (default: the recording's path, with the extension '.cast')")
+	exportCmd.Flags().StringVar(&exportSpeed, "speed", "1x",
+		"How fast the export plays, e.g., '1x' (as fast as it was recorded), or '10x'")
+	exportCmd.Flags().StringVar(&exportSeek, "seek", "",
+		"Start the export at a timestamp (e.g., '2024-01-02T15:04:05Z'), an offset from the start of "+
+			"the recording (e.g., '90s'), or an event number (e.g., '#12')")
+	exportCmd.Flags().DurationVar(&exportIdleTimeLimit, "idle-time-limit", 0,
+		"Longest pause between events players show, e.g., '3s' (default: no limit)")
+	exportCmd.Flags().IntVar(&exportWidth, "width", 0,
+		"Width of the terminal, in columns (default: wide enough for every event, and at least 80)")
+	exportCmd.Flags().IntVar(&exportHeight, "height", 0,
+		"Height of the terminal, in rows (default: tall enough for every event, and at least 24)")
+	rootCmd.AddCommand(exportCmd)
+}
+
+// unknownTimeFrame is how long each event is shown for in an export, if the recording does not
+// have the time it happened.
+const unknownTimeFrame = time.Second
+
+var exportCmd = &cobra.Command{
+	Use:   "export <recording>...",
+	Short: "Exports a session recorded with 'kubespy record', e.g., as an asciinema cast",
+	Long: `Exports a session recorded with 'kubespy record', e.g., as an asciinema cast that plays the
+recording back as 'kubespy replay --view trace' would, at the pace it was recorded. Unless --color
+is set, the export is colored. The files of a rotated recording are exported together.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if exportFormat != "asciicast" {
+			log.Fatalf("Unknown export format '%s'; must be: asciicast", exportFormat)
+		}
+		if exportView != "trace" {
+			log.Fatalf("Unknown view '%s'; must be: trace", exportView)
+		}
+
+		index, err := recording.IndexFiles(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(index.Times) == 0 {
+			log.Fatal("Recording is empty")
+		}
+		speed, err := replay.ParseSpeed(exportSpeed)
+		if err != nil {
+			log.Fatal(err)
+		} else if speed == 0 {
+			log.Fatal("--speed max would show every event at once; export at, e.g., '100x' instead")
+		}
+		from, err := replay.Seek(index.Times, exportSeek)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// The cast is played back on a terminal, whatever it is written to.
+		if !cmd.Flags().Changed("color") {
+			theme := print.CurrentTheme()
+			theme.Color = print.ColorAlways
+			if err := print.SetTheme(theme); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		path := exportOutput
+		if path == "" {
+			path = strings.TrimSuffix(args[0], filepath.Ext(args[0])) + ".cast"
+		}
+		out, err := os.Create(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer out.Close()
+
+		opts := export.CastOptions{
+			Width:         exportWidth,
+			Height:        exportHeight,
+			Title:         filepath.Base(args[0]),
+			Timestamp:     index.Times[from],
+			IdleTimeLimit: exportIdleTimeLimit,
+		}
+		frames := traceFrames(args, index, from, speed)
+		if err := export.WriteCast(out, frames, opts); err != nil {
+			log.Fatal(err)
+		}
+		print.Banner(os.Stderr, "Wrote cast of %d frames to %s", len(frames), path)
+	},
+}
+
+// traceFrames renders the trace of the object recorded in the files `paths`, which `index` indexes,
+// after each event, from the event `from` on, timed as if played back at `speed`. Events that don't
+// change the trace as rendered get no frame of their own.
+func traceFrames(paths []string, index *recording.Index, from int, speed float64) []export.Frame {
+	apiVersion, kind, namespace, name, _ := index.Subject()
+	snapshots := trace.NewSnapshotter(apiVersion, kind, namespace, name,
+		index.EventsRegardSubject())
+	offsets := replay.Offsets(index.Times[from:], speed, unknownTimeFrame)
+
+	events := openRecording(paths)
+	defer events.Close()
+	frames := []export.Frame{}
+	for i := 0; i < len(index.Times) && events.Scan(); i++ {
+		e := events.Event()
+		snapshots.Observe(k8sWatch.Event{Type: e.Type, Object: e.Object})
+		if i < from {
+			continue
+		}
+
+		var b bytes.Buffer
+		print.WriteText(&b, snapshots.Model())
+		if len(frames) > 0 && frames[len(frames)-1].Text == b.String() {
+			continue
+		}
+		frames = append(frames, export.Frame{At: offsets[i-from], Text: b.String()})
+	}
+	if err := events.Err(); err != nil {
+		log.Fatal(err)
+	}
+	return frames
+}
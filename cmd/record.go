@@ -15,88 +15,253 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"reflect"
 	"syscall"
+	"time"
 
+	"github.com/pulumi/kubespy/k8sconfig"
+	"github.com/pulumi/kubespy/recording"
+	"github.com/pulumi/kubespy/version"
 	"github.com/pulumi/kubespy/watch"
 	"github.com/spf13/cobra"
-	"github.com/yudai/gojsondiff"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
 	apiwatch "k8s.io/apimachinery/pkg/watch"
 )
 
+var (
+	recordFormat         string
+	recordKinds          []string
+	recordOutput         string
+	recordCompression    string
+	recordRotateSize     string
+	recordRotateInterval time.Duration
+	recordDiffs          bool
+)
+
 func init() {
+	recordCmd.Flags().StringVar(&recordFormat, "format", "ndjson",
+		"Recording format. One of: ndjson (a header, then one event per line), legacy-array (a JSON "+
+			"array of the object's states, without event types, times, or deletions)")
+	recordCmd.Flags().StringArrayVarP(&recordKinds, "kind", "k", []string{},
+		"Record every object of this resource type (e.g., 'deploy', 'pods', or 'configmaps'). May be "+
+			"given more than once")
+	recordCmd.Flags().StringVarP(&labelSelector, "selector", "l", "",
+		"Only record objects matching this label selector, e.g., 'app=nginx'")
+	recordCmd.Flags().StringVarP(&namespaceFlag, "namespace", "n", "",
+		"Namespace to record, if it isn't given as part of the name. Defaults to the current context's")
+	recordCmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false,
+		"Record objects in every namespace")
+	addEventsFlag(recordCmd.Flags())
+	recordCmd.Flags().StringVarP(&recordOutput, "output", "o", "",
+		"File to write the recording to (default: stdout)")
+	recordCmd.Flags().StringVar(&recordCompression, "compress", "",
+		"How to compress the --output file. One of: none, gzip, zstd (default: by the file's "+
+			"extension, '.gz' or '.zst')")
+	recordCmd.Flags().StringVar(&recordRotateSize, "rotate-size", "",
+		"Continue the recording in a new --output file once the current one reaches this size, "+
+			"e.g., '100Mi'")
+	recordCmd.Flags().DurationVar(&recordRotateInterval, "rotate-interval", 0,
+		"Continue the recording in a new --output file once the current one is this old, e.g., '24h'")
+	recordCmd.Flags().BoolVar(&recordDiffs, "diffs", false,
+		"Record only the first state of each object in full, and each later state as a JSON Patch "+
+			"of the one before")
 	rootCmd.AddCommand(recordCmd)
 }
 
-// SetupCloseHandler catches the ctrl+c signal and properly terminates the JSON array before exiting.
-func SetupCloseHandler() {
-	c := make(chan os.Signal, 2)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-c
-		fmt.Println("\n]")
-		os.Exit(0)
-	}()
-}
-
 var recordCmd = &cobra.Command{
-	Use:   "record <apiVersion> <kind> [<namespace>/]<name>",
-	Short: "Displays events generated by a Kubernetes resource in real time. Emitted as a JSON array.",
-	Args:  cobra.ExactArgs(3),
+	Use:   "record [<apiVersion> <kind> [[<namespace>/]<name>]] [-k <type>]...",
+	Short: "Records events generated by Kubernetes resources in real time, for later inspection",
+	Long: `Records events generated by Kubernetes resources in real time, for later inspection with
+'kubespy report' or 'kubespy replay'. Records one object if its name is given, and otherwise every
+object of the kind (and of every kind given with -k) in the namespace, or those matching --selector,
+all interleaved in one recording. With --events, the Kubernetes Events regarding the objects are
+recorded too.
+
+By default, writes a header describing the recording, followed by one JSON event per line, each with
+its time, type, GVK, and object. Use '--format legacy-array' for the original format: a JSON array
+of the objects' states.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		switch {
+		case len(args) == 1 || len(args) > 3:
+			return fmt.Errorf("Expected <apiVersion> <kind> [[<namespace>/]<name>], got %d arguments",
+				len(args))
+		case len(args) == 0 && len(recordKinds) == 0:
+			return fmt.Errorf("Nothing to record: give an <apiVersion> and <kind>, or a type with -k")
+		case len(args) == 3 && (len(recordKinds) > 0 || labelSelector != "" || allNamespaces):
+			return fmt.Errorf(
+				"Can't record the object '%s' and also -k, --selector, or --all-namespaces", args[2])
+		}
+		return nil
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		namespace, name, err := parseObjID(args[2])
+		filters, kinds, opts, err := recordTargets(args)
 		if err != nil {
 			log.Fatal(err)
 		}
+		filters.Events = withEvents
+
+		var newWriter func(w io.Writer) (recording.Writer, error)
+		switch recordFormat {
+		case "ndjson":
+			header := recording.Header{
+				KubespyVersion: version.Version,
+				Command:        os.Args,
+				Filters:        filters,
+				Diffs:          recordDiffs,
+			}
+			// The recording is still useful without these.
+			header.Context, header.Cluster, _ = k8sconfig.CurrentContext()
+			newWriter = func(w io.Writer) (recording.Writer, error) {
+				header.Time = time.Now().UTC()
+				return recording.NewWriter(w, header)
+			}
+		case "legacy-array":
+			if recordDiffs {
+				log.Fatal("--diffs requires --format ndjson")
+			}
+			newWriter = func(w io.Writer) (recording.Writer, error) {
+				return recording.NewLegacyWriter(w), nil
+			}
+		default:
+			log.Fatal(fmt.Errorf("Unknown recording format '%s'; must be one of: ndjson, legacy-array",
+				recordFormat))
+		}
 
-		events, err := watch.Forever(args[0], args[1], watch.ThisObject(namespace, name))
+		writer, err := newRecordWriter(newWriter)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		fmt.Print("[\n  ")
-
-		var last *unstructured.Unstructured
-		for {
-			select {
-			case e := <-events:
-				o := e.Object.(*unstructured.Unstructured)
-				switch e.Type {
-				case apiwatch.Added:
-					if last != nil {
-						fmt.Println(",")
-					}
-
-					SetupCloseHandler()
-
-					if output, err := json.MarshalIndent(o.Object, "  ", "  "); err != nil {
-						log.Fatal(err)
-					} else {
-						fmt.Print(string(output))
-					}
-				case apiwatch.Modified:
-					diff := gojsondiff.New().CompareObjects(last.Object, o.Object)
-					if diff.Modified() {
-						if last != nil {
-							fmt.Println(",")
-						}
-						fmt.Print("  ")
-						if output, err := json.MarshalIndent(o.Object, "  ", "  "); err != nil {
-							log.Fatal(err)
-						} else {
-							fmt.Print(string(output))
-						}
-					}
-				case apiwatch.Deleted:
-					// Nothing to print.
+		objects := make(chan apiwatch.Event)
+		for _, kind := range kinds {
+			kindEvents, err := watch.Forever(kind[0], kind[1], opts)
+			if err != nil {
+				log.Fatal(err)
+			}
+			go func() {
+				for e := range kindEvents {
+					objects <- e
 				}
-				last = o
+			}()
+		}
+
+		var events <-chan apiwatch.Event = objects
+		if withEvents {
+			if events, err = followKubeEvents(opts.Namespace(), objects); err != nil {
+				log.Fatal(err)
 			}
 		}
+		record(writer, events)
 	},
 }
+
+// newRecordWriter creates the Writer `record` writes to: a new one for stdout, or one that writes
+// to the --output file, compressing and rotating it as requested.
+func newRecordWriter(
+	newWriter func(w io.Writer) (recording.Writer, error),
+) (recording.Writer, error) {
+	if recordOutput == "" {
+		if recordCompression != "" || recordRotateSize != "" || recordRotateInterval != 0 {
+			return nil, fmt.Errorf("--compress, --rotate-size, and --rotate-interval require --output")
+		}
+		return newWriter(os.Stdout)
+	}
+
+	opts := recording.FileOptions{RotateInterval: recordRotateInterval}
+	compression, err := recording.ParseCompression(recordCompression, recordOutput)
+	if err != nil {
+		return nil, err
+	}
+	opts.Compression = compression
+	if recordRotateSize != "" {
+		size, err := resource.ParseQuantity(recordRotateSize)
+		if err != nil || size.Value() <= 0 {
+			return nil, fmt.Errorf("Invalid --rotate-size '%s'; must be a size, e.g., '100Mi'",
+				recordRotateSize)
+		}
+		opts.RotateSize = size.Value()
+	}
+	return recording.NewFileWriter(recordOutput, opts, newWriter)
+}
+
+// recordTargets decides which objects `record` records from its arguments and flags: the kinds
+// (each as an apiVersion and kind) to watch, and which of their objects to record.
+func recordTargets(args []string) (
+	filters recording.Filters, kinds [][2]string, opts watch.Opts, err error,
+) {
+	if len(args) == 3 {
+		namespace, name, err := parseTargetID(args[2])
+		if err != nil {
+			return filters, nil, opts, err
+		}
+		filters = recording.Filters{
+			Kinds: []string{args[0] + "/" + args[1]}, Namespace: namespace, Name: name,
+		}
+		return filters, [][2]string{{args[0], args[1]}}, watch.ThisObject(namespace, name), nil
+	}
+
+	if len(args) == 2 {
+		kinds = append(kinds, [2]string{args[0], args[1]})
+	}
+	for _, resourceType := range recordKinds {
+		apiVersion, kind, err := watch.ResolveType(resourceType)
+		if err != nil {
+			return filters, nil, opts, err
+		}
+		kinds = append(kinds, [2]string{apiVersion, kind})
+	}
+
+	namespace, err := targetNamespace()
+	if err != nil {
+		return filters, nil, opts, err
+	}
+
+	filters = recording.Filters{Kinds: []string{}, Namespace: namespace, Selector: labelSelector}
+	for _, kind := range kinds {
+		filters.Kinds = append(filters.Kinds, kind[0]+"/"+kind[1])
+	}
+	if labelSelector != "" {
+		return filters, kinds, watch.ObjectsLabeled(namespace, labelSelector), nil
+	}
+	return filters, kinds, watch.All(namespace), nil
+}
+
+// record writes every event in `events` to `writer` until interrupted, then closes it. Events that
+// don't change their object (e.g., those caused by a resync) are skipped.
+func record(writer recording.Writer, events <-chan apiwatch.Event) {
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+
+	last := map[types.UID]*unstructured.Unstructured{}
+	for {
+		select {
+		case e := <-events:
+			o := e.Object.(*unstructured.Unstructured)
+			if e.Type == apiwatch.Modified && reflect.DeepEqual(last[o.GetUID()], o) {
+				continue
+			}
+			if e.Type == apiwatch.Deleted {
+				delete(last, o.GetUID())
+			} else {
+				last[o.GetUID()] = o
+			}
+
+			err := writer.Write(recording.Event{Time: time.Now(), Type: e.Type, Object: o})
+			if err != nil {
+				log.Fatal(err)
+			}
+		case <-interrupted:
+			if err := writer.Close(); err != nil {
+				log.Fatal(err)
+			}
+			os.Exit(0)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/pulumi/kubespy/diff"
+	"github.com/pulumi/kubespy/print"
+	"github.com/pulumi/kubespy/recording"
+	"github.com/pulumi/kubespy/replay"
+	"github.com/pulumi/kubespy/trace"
+	"github.com/spf13/cobra"
+	k8sWatch "k8s.io/apimachinery/pkg/watch"
+)
+
+var (
+	replayView  string
+	replaySpeed string
+	replayStep  bool
+	replaySeek  string
+	replayPath  string
+)
+
+func init() {
+	addDiffFlags(replayCmd.Flags(), &replayPath, ".")
+	replayCmd.Flags().StringVar(&replayView, "view", "changes",
+		"How to show the recording. One of: changes, status (as 'kubespy changes' and 'kubespy status' "+
+			"would), trace (as 'kubespy trace' would for Deployments and Services, and 'kubespy tree' "+
+			"would for anything else)")
+	replayCmd.Flags().StringVar(&replaySpeed, "speed", "1x",
+		"How fast to play the recording, e.g., '1x' (as fast as it was recorded), '10x', or 'max'")
+	replayCmd.Flags().BoolVar(&replayStep, "step", false,
+		"Wait for enter to be pressed before showing each event")
+	replayCmd.Flags().StringVar(&replaySeek, "seek", "",
+		"Start playing at a timestamp (e.g., '2024-01-02T15:04:05Z'), an offset from the start of "+
+			"the recording (e.g., '90s'), or an event number (e.g., '#12')")
+	replayCmd.Flags().StringVarP(&output, "output", "o", "text",
+		"Output format of the trace view. One of: text, json (one JSON document per line, each time "+
+			"the trace changes)")
+	rootCmd.AddCommand(replayCmd)
+}
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <recording>...",
+	Short: "Plays back a session recorded with 'kubespy record'",
+	Long: `Plays back a session recorded with 'kubespy record', with the same views 'kubespy changes',
+'kubespy status', and 'kubespy trace' show live. By default, events are played at the pace they
+were recorded. The files of a rotated recording are played one after another.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// The recording is read once to find where to start, and again as it is played, so that
+		// only the latest state of each object need be held in memory.
+		index, err := recording.IndexFiles(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(index.Times) == 0 {
+			log.Fatal("Recording is empty")
+		}
+
+		opts := replay.Options{}
+		if opts.Speed, err = replay.ParseSpeed(replaySpeed); err != nil {
+			log.Fatal(err)
+		}
+		if opts.From, err = replay.Seek(index.Times, replaySeek); err != nil {
+			log.Fatal(err)
+		}
+		if replayStep {
+			opts.Step = os.Stdin
+			print.Note(os.Stderr, "Press enter to show each event")
+		}
+
+		switch replayView {
+		case "changes", "status":
+			path := replayPath
+			if replayView == "status" && !cmd.Flags().Changed("path") {
+				path = ".status"
+			}
+			replayChanges(args, index, path, opts)
+		case "trace":
+			replayTrace(args, index, opts)
+		default:
+			log.Fatalf("Unknown view '%s'; must be one of: changes, status, trace", replayView)
+		}
+	},
+}
+
+// replayChanges plays back the changes to the part at `pathSpec` of every object recorded in the
+// files `paths`, which `index` indexes.
+func replayChanges(paths []string, index *recording.Index, pathSpec string, opts replay.Options) {
+	path, err := diff.ParsePath(pathSpec)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The recording has no schema, so the elements of well-known lists are matched up by their
+	// usual keys, as they are when watching objects whose schema the API server doesn't publish.
+	printer := newChangePrinter(path, nil, index.Objects > 1)
+
+	events := openRecording(paths)
+	defer events.Close()
+	err = replay.Play(events, opts, func(i int, e recording.Event, show bool) {
+		printer.note = eventNote(i, len(index.Times), e.Time)
+		printer.observe(k8sWatch.Event{Type: e.Type, Object: e.Object}, show)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// replayTrace plays back the trace of the object recorded in the files `paths`, which `index`
+// indexes.
+func replayTrace(paths []string, index *recording.Index, opts replay.Options) {
+	apiVersion, kind, namespace, name, _ := index.Subject()
+	snapshots := trace.NewSnapshotter(apiVersion, kind, namespace, name,
+		index.EventsRegardSubject())
+
+	var renderer print.Renderer
+	if opts.Step != nil && output == "text" {
+		// Pressing enter would scroll a trace that is redrawn in place.
+		renderer = print.NewPlainRenderer(os.Stdout)
+	} else {
+		var err error
+		if renderer, err = newRenderer(output, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	}
+	defer renderer.Close()
+
+	events := openRecording(paths)
+	defer events.Close()
+	err := replay.Play(events, opts, func(i int, e recording.Event, show bool) {
+		snapshots.Observe(k8sWatch.Event{Type: e.Type, Object: e.Object})
+		if show {
+			render(renderer, snapshots.Model())
+		}
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// openRecording begins reading the recording in the files `paths` (again), one event at a time.
+func openRecording(paths []string) *recording.Scanner {
+	events, err := recording.OpenFiles(paths)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return events
+}
+
+// eventNote describes the event `i` of `n`, which was recorded at `t`.
+func eventNote(i, n int, t time.Time) string {
+	if t.IsZero() {
+		return fmt.Sprintf("#%d/%d", i+1, n)
+	}
+	return fmt.Sprintf("#%d/%d at %s", i+1, n, t.Local().Format(time.RFC3339))
+}
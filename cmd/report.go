@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pulumi/kubespy/print"
+	"github.com/pulumi/kubespy/recording"
+	"github.com/pulumi/kubespy/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportOutput  string
+	reportSummary string
+)
+
+func init() {
+	reportCmd.Flags().StringVarP(&reportOutput, "output", "o", "",
+		"File to write the report to (default: the recording's path, with the extension '.html')")
+	reportCmd.Flags().StringVar(&reportSummary, "summary", "",
+		"Instead of an HTML report, write a summary of a recorded Deployment rollout to stdout. "+
+			"One of: markdown")
+	rootCmd.AddCommand(reportCmd)
+}
+
+var reportCmd = &cobra.Command{
+	Use:   "report <recording>...",
+	Short: "Generates a self-contained HTML report of a session recorded with 'kubespy record'",
+	Long: `Generates a self-contained HTML report of a session recorded with 'kubespy record', which can
+be viewed offline by people without access to the cluster. The report contains a timeline of every
+recorded change, the diff of each, and the state of the recorded object's trace after each. The
+files of a rotated recording are reported on together.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		rec, err := recording.ReadFiles(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		switch reportSummary {
+		case "":
+		case "markdown":
+			s, err := report.Summarize(rec)
+			if err != nil {
+				log.Fatal(err)
+			}
+			print.WriteMarkdownSummary(os.Stdout, s)
+			return
+		default:
+			log.Fatalf("Unknown summary format '%s'; must be: markdown", reportSummary)
+		}
+
+		path := reportOutput
+		if path == "" {
+			path = strings.TrimSuffix(args[0], filepath.Ext(args[0])) + ".html"
+		}
+		// A recording that was itself named `*.html` would otherwise be overwritten by its report.
+		if out, err := os.Stat(path); err == nil {
+			for _, arg := range args {
+				if in, err := os.Stat(arg); err == nil && os.SameFile(in, out) {
+					log.Fatalf("Refusing to overwrite the recording '%s' with its report; "+
+						"choose where to write it with -o", arg)
+				}
+			}
+		}
+		out, err := os.Create(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer out.Close()
+
+		// Escape codes would be displayed literally in the report.
+		theme := print.CurrentTheme()
+		theme.Color = print.ColorNever
+		if err := print.SetTheme(theme); err != nil {
+			log.Fatal(err)
+		}
+
+		if err := report.Write(out, filepath.Base(args[0]), rec); err != nil {
+			log.Fatal(err)
+		}
+		print.Banner(os.Stderr, "Wrote report of %d events to %s", len(rec.Events), path)
+	},
+}
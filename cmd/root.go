@@ -6,12 +6,40 @@ import (
 	"strings"
 
 	"github.com/pulumi/kubespy/k8sconfig"
+	"github.com/pulumi/kubespy/print"
 	"github.com/spf13/cobra"
 )
 
+var (
+	colorMode string
+	glyphs    string
+	palette   string
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", string(print.ColorAuto),
+		"When to color output. One of: auto (only on a terminal, and only if NO_COLOR is not set), always, never")
+	rootCmd.PersistentFlags().StringVar(&glyphs, "glyphs", "emoji",
+		"Status symbols to print. One of: emoji, ascii (for screen readers and log aggregators)")
+	rootCmd.PersistentFlags().StringVar(&palette, "palette", string(print.PaletteDark),
+		"Colors to print with. One of: dark, light (for terminals with a light background)")
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "kubespy <command>",
 	Short: "Spy on your Kubernetes resources",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		theme := print.Theme{Color: print.ColorMode(colorMode), Palette: print.Palette(palette)}
+		switch glyphs {
+		case "emoji":
+			theme.Glyphs = print.EmojiGlyphs
+		case "ascii":
+			theme.Glyphs = print.ASCIIGlyphs
+		default:
+			return fmt.Errorf("Unknown glyphs '%s'; must be one of: emoji, ascii", glyphs)
+		}
+		return print.SetTheme(theme)
+	},
 }
 
 func Execute() {
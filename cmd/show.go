@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/pulumi/kubespy/diff"
+	"github.com/pulumi/kubespy/print"
+	"github.com/pulumi/kubespy/recording"
+	"github.com/pulumi/kubespy/replay"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	apiwatch "k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	showAt      string
+	showBetween string
+	showOutput  string
+	showPath    string
+)
+
+func init() {
+	addDiffFlags(showCmd.Flags(), &showPath, ".")
+	showCmd.Flags().StringVar(&showAt, "at", "",
+		"Show the state of every object as of a timestamp (e.g., '2024-01-02T15:04:05Z'), an offset "+
+			"from the start of the recording (e.g., '90s'), or an event number (e.g., '#12')")
+	showCmd.Flags().StringVar(&showBetween, "between", "",
+		"Show the net changes to every object between this position and the one given as the last "+
+			"argument, e.g., '--between 90s 5m'")
+	showCmd.Flags().StringVarP(&showOutput, "output", "o", "yaml",
+		"Output format of --at. One of: yaml, json")
+	rootCmd.AddCommand(showCmd)
+}
+
+var showCmd = &cobra.Command{
+	Use:   "show <recording>... (--at <position> | --between <position> <position>)",
+	Short: "Shows the state of the objects in a session recorded with 'kubespy record' at a moment",
+	Long: `Shows the state of the objects in a session recorded with 'kubespy record' at a moment, without
+replaying everything before it. With --at, prints the full state of every object recorded, as of
+that moment, as a List. With --between, prints the net changes to every object between two moments,
+diffed as 'kubespy changes' would. A position is a timestamp (e.g., '2024-01-02T15:04:05Z'), an
+offset from the start of the recording (e.g., '90s'), or an event number (e.g., '#12'), and includes
+every event up to and including it. The files of a rotated recording are read together.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		switch {
+		case (showAt == "") == (showBetween == ""):
+			return fmt.Errorf("Expected exactly one of --at or --between")
+		case showBetween != "" && len(args) < 2:
+			return fmt.Errorf("Expected <recording>... --between <position> <position>")
+		case len(args) < 1:
+			return fmt.Errorf("Expected at least one <recording>")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		paths, from, to := args, showAt, ""
+		if showBetween != "" {
+			// The second position of `--between` is the last argument.
+			paths, from, to = args[:len(args)-1], showBetween, args[len(args)-1]
+		}
+
+		// The recording is read once to find the positions, and again up to them, so that only the
+		// state of each object at them need be held in memory.
+		index, err := recording.IndexFiles(paths)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(index.Times) == 0 {
+			log.Fatal("Recording is empty")
+		}
+
+		n, err := replay.Upto(index.Times, from)
+		if err != nil {
+			log.Fatal(err)
+		}
+		m := n
+		if to != "" {
+			if m, err = replay.Upto(index.Times, to); err != nil {
+				log.Fatal(err)
+			} else if m < n {
+				log.Fatalf("'%s' is before '%s'", to, from)
+			}
+		}
+
+		events := openRecording(paths)
+		defer events.Close()
+		before := map[types.UID]*unstructured.Unstructured{}
+		if err := replay.States(events, n, before); err != nil {
+			log.Fatal(err)
+		}
+		if to == "" {
+			print.Note(os.Stderr, positionNote(index, n))
+			showStates(before)
+			return
+		}
+
+		after := map[types.UID]*unstructured.Unstructured{}
+		for uid, o := range before {
+			after[uid] = o
+		}
+		if err := replay.States(events, m-n, after); err != nil {
+			log.Fatal(err)
+		}
+		path, err := diff.ParsePath(showPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		print.Banner(os.Stdout, "Changes from %s to %s", positionNote(index, n),
+			positionNote(index, m))
+		showChanges(before, after, path)
+	},
+}
+
+// positionNote describes the moment after the first `n` events of the recording `index` indexes.
+func positionNote(index *recording.Index, n int) string {
+	if n == 0 {
+		return "the start of the recording"
+	}
+	return "event " + eventNote(n-1, len(index.Times), index.Times[n-1])
+}
+
+// showStates prints `states` as a List, in the format given by `--output`.
+func showStates(states map[types.UID]*unstructured.Unstructured) {
+	items := []interface{}{}
+	for _, o := range sortedStates(states) {
+		items = append(items, o.Object)
+	}
+	list := map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": items}
+
+	var out []byte
+	var err error
+	switch showOutput {
+	case "yaml":
+		out, err = yaml.Marshal(list)
+	case "json":
+		out, err = json.MarshalIndent(list, "", "  ")
+		out = append(out, '\n')
+	default:
+		log.Fatalf("Unknown output format '%s'; must be one of: yaml, json", showOutput)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Stdout.Write(out)
+}
+
+// showChanges prints the net changes to the part at `path` of each object from its state in
+// `before` to its state in `after`.
+func showChanges(before, after map[types.UID]*unstructured.Unstructured, path diff.Path) {
+	// The recording has no schema, so the elements of well-known lists are matched up by their
+	// usual keys, as they are when watching objects whose schema the API server doesn't publish.
+	printer := newChangePrinter(path, nil, true)
+	for _, o := range sortedStates(before) {
+		printer.observe(apiwatch.Event{Type: apiwatch.Added, Object: o}, false)
+	}
+
+	all := map[types.UID]*unstructured.Unstructured{}
+	for uid, o := range before {
+		all[uid] = o
+	}
+	for uid, o := range after {
+		all[uid] = o
+	}
+
+	changed := false
+	for _, o := range sortedStates(all) {
+		e := apiwatch.Event{Type: apiwatch.Modified, Object: o}
+		if _, exists := before[o.GetUID()]; !exists {
+			e.Type = apiwatch.Added
+		} else if _, exists := after[o.GetUID()]; !exists {
+			e.Type = apiwatch.Deleted
+		}
+		if printer.observe(e, true) {
+			changed = true
+		}
+	}
+	if !changed {
+		print.Note(os.Stdout, "No changes")
+	}
+}
+
+// sortedStates returns `states` ordered by kind, namespace, and name.
+func sortedStates(states map[types.UID]*unstructured.Unstructured) []*unstructured.Unstructured {
+	sorted := make([]*unstructured.Unstructured, 0, len(states))
+	for _, o := range states {
+		sorted = append(sorted, o)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.GetKind() != b.GetKind() {
+			return a.GetKind() < b.GetKind()
+		} else if a.GetNamespace() != b.GetNamespace() {
+			return a.GetNamespace() < b.GetNamespace()
+		}
+		return a.GetName() < b.GetName()
+	})
+	return sorted
+}
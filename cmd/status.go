@@ -1,79 +1,95 @@
 package cmd
 
 import (
-	"encoding/json"
-	"fmt"
 	"log"
+	"os"
+	"time"
 
-	"github.com/fatih/color"
+	"github.com/pulumi/kubespy/diff"
+	"github.com/pulumi/kubespy/print"
+	"github.com/pulumi/kubespy/trace"
 	"github.com/pulumi/kubespy/watch"
 	"github.com/spf13/cobra"
-	"github.com/yudai/gojsondiff"
-	"github.com/yudai/gojsondiff/formatter"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var (
+	statusPath     string
+	conditionsView bool
 )
 
 func init() {
+	addChangesFlags(statusCmd.Flags(), &statusPath, ".status")
+	statusCmd.Flags().BoolVar(&conditionsView, "conditions", false,
+		"Instead of diffs, display a live table of the object's status conditions, with the history "+
+			"of each, and whether its controller has observed its latest generation")
 	rootCmd.AddCommand(statusCmd)
 }
 
 var statusCmd = &cobra.Command{
-	Use:   "status <apiVersion> <kind> [<namespace>/]<name>",
+	Use:   "status <apiVersion> <kind> [[<namespace>/]<name>]",
 	Short: "Displays changes to a Kubernetes resources's status in real time. Emitted as JSON diffs",
-	Args:  cobra.ExactArgs(3),
+	Long: `Displays changes to a Kubernetes resources's status in real time. Emitted as JSON diffs.
+Equivalent to 'kubespy changes --path .status'. If no name is given, displays changes to the status
+of every object of the kind in the namespace (or those matching --selector).`,
+	Args: cobra.RangeArgs(2, 3),
 	Run: func(cmd *cobra.Command, args []string) {
-		namespace, name, err := parseObjID(args[2])
+		opts, many, desc, err := changesTarget(args)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		events, err := watch.Forever(args[0], args[1], watch.ThisObject(namespace, name))
+		if conditionsView {
+			if many {
+				log.Fatal("--conditions requires the name of an object")
+			} else if withEvents {
+				log.Fatal("--events can't be shown with --conditions")
+			}
+			namespace, name, err := parseTargetID(args[2])
+			if err != nil {
+				log.Fatal(err)
+			}
+			watchConditions(args[0], args[1], namespace, name, opts)
+			return
+		}
+
+		path, err := diff.ParsePath(statusPath)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		fmt.Println(color.GreenString("Watching status of %s %s %s", args[0], args[1], args[2]))
+		print.Banner(os.Stdout, "Watching status of %s", desc)
+		watchChanges(args[0], args[1], opts, many, path)
+	},
+}
 
-		heading := color.New(color.FgBlue, color.Bold)
+// watchConditions displays a live table of the status conditions of the object `opts` selects,
+// forever.
+func watchConditions(apiVersion, kind, namespace, name string, opts watch.Opts) {
+	events, err := watch.Forever(apiVersion, kind, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		var lastStatus map[string]interface{}
-		for {
-			select {
-			case e := <-events:
-				o := e.Object.(*unstructured.Unstructured)
-				var currStatus map[string]interface{}
-				if status, hasStatus := o.Object["status"]; !hasStatus {
-					currStatus = make(map[string]interface{})
-				} else if status, isMap := status.(map[string]interface{}); !isMap {
-					currStatus = make(map[string]interface{})
-				} else {
-					currStatus = status
-				}
+	renderer := print.NewRenderer(os.Stdout)
+	defer renderer.Close()
 
-				if lastStatus == nil {
-					heading.Println("CREATED")
+	conditions := trace.NewConditions(apiVersion, kind, namespace, name)
+	render(renderer, conditions)
 
-					ojson, err := json.MarshalIndent(currStatus, "", "  ")
-					if err != nil {
-						log.Fatal(err)
-					}
-					fmt.Println(color.GreenString(string(ojson)))
-				} else {
-					heading.Println(string(e.Type))
+	// Re-render periodically even if nothing changes, so that times since transitions stay current.
+	var tick <-chan time.Time
+	if print.IsLive(renderer) {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
 
-					diff := gojsondiff.New().CompareObjects(lastStatus, currStatus)
-					if diff.Modified() {
-						fcfg := formatter.AsciiFormatterConfig{Coloring: true}
-						formatter := formatter.NewAsciiFormatter(lastStatus, fcfg)
-						text, err := formatter.Format(diff)
-						if err != nil {
-							log.Fatal(err)
-						}
-						fmt.Println(text)
-					}
-				}
-				lastStatus = currStatus
-			}
+	for {
+		select {
+		case e := <-events:
+			conditions.Observe(e, time.Now())
+		case <-tick:
 		}
-	},
+		render(renderer, conditions)
+	}
 }
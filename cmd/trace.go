@@ -3,27 +3,36 @@ package cmd
 import (
 	"fmt"
 	"log"
+	"os"
 	"strings"
 	"time"
 
-	"github.com/fatih/color"
-	"github.com/mbrlabs/uilive"
+	"github.com/pulumi/kubespy/pods"
 	"github.com/pulumi/kubespy/print"
+	"github.com/pulumi/kubespy/trace"
 	"github.com/pulumi/kubespy/watch"
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	k8sWatch "k8s.io/apimachinery/pkg/watch"
 )
 
-const (
-	v1Endpoints  = "v1/Endpoints"
-	v1Service    = "v1/Service"
-	v1Pod        = "v1/Pod"
-	deployment   = "Deployment"
-	v1ReplicaSet = "v1/ReplicaSet"
+var (
+	showLogs bool
+	logLines int64
+	output   string
+	summary  string
 )
 
 func init() {
+	traceCmd.Flags().StringVarP(&output, "output", "o", "text",
+		"Output format. One of: text, json (one JSON document per line, each time the trace changes)")
+	traceCmd.Flags().BoolVar(&showLogs, "logs", false,
+		"Show the tail of the log of every failing container beneath its error")
+	traceCmd.Flags().Int64Var(&logLines, "log-lines", 10, "Number of log lines to show with --logs")
+	traceCmd.Flags().StringVar(&summary, "summary", "",
+		"Once a Deployment's rollout succeeds or fails, exit and write a summary of it to stdout "+
+			"(the trace is written to stderr instead). One of: markdown")
+	addEventsFlag(traceCmd.Flags())
 	rootCmd.AddCommand(traceCmd)
 }
 
@@ -32,7 +41,10 @@ var traceCmd = &cobra.Command{
 	Short: "Traces status of complex API objects",
 	Long: `Traces status of complex API objects. Accepted types are:
   - service (aliases: {svc})
-  - deployment (aliases: {deploy})`,
+  - deployment (aliases: {deploy})
+
+With --events, the most recent Kubernetes Events regarding the object, and every object it owns, are
+shown beneath the trace.`,
 	Args: cobra.ExactArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
 		namespace, name, err := parseObjID(args[1])
@@ -40,11 +52,35 @@ var traceCmd = &cobra.Command{
 			log.Fatal(err)
 		}
 
+		out := os.Stdout
+		switch summary {
+		case "":
+		case "markdown":
+			out = os.Stderr
+		default:
+			log.Fatalf("Unknown summary format '%s'; must be: markdown", summary)
+		}
+
+		renderer, err := newRenderer(output, out)
+		if err != nil {
+			log.Fatal(err)
+		}
+
 		switch t := strings.ToLower(args[0]); t {
 		case "service", "svc":
-			traceService(namespace, name)
+			if summary != "" {
+				log.Fatal("--summary is only supported for deployments")
+			}
+			traceService(renderer, namespace, name)
 		case "deployment", "deploy":
-			traceDeployment(namespace, name)
+			s := traceDeployment(renderer, namespace, name, summary != "")
+			if err := renderer.Close(); err != nil {
+				log.Fatal(err)
+			}
+			print.WriteMarkdownSummary(os.Stdout, s)
+			if s.Verdict == trace.Failed {
+				os.Exit(1)
+			}
 		default:
 			msg := "Unknown resource type '%s'. The following resources are available:\n" +
 				"  - service (aliases: {svc})\n" +
@@ -54,40 +90,53 @@ var traceCmd = &cobra.Command{
 	},
 }
 
-func traceService(namespace, name string) {
+func traceService(renderer print.Renderer, namespace, name string) {
 	serviceEvents, err := watch.Forever("v1", "Service", watch.ThisObject(namespace, name))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// NOTE: We can use the same watch opts here because the `Endpoints` object will have the same
-	// name and be in the same namespace.
-	endpointEvents, err := watch.Forever("v1", "Endpoints", watch.ThisObject(namespace, name))
+	// EndpointSlices are not named after the Service, but the EndpointSlice controller labels each
+	// of them with the Service's name.
+	var endpointEvents <-chan k8sWatch.Event
+	endpointSliceEvents, err := watch.Forever("discovery.k8s.io/v1", "EndpointSlice",
+		watch.ObjectsLabeled(namespace, "kubernetes.io/service-name="+name))
 	if err != nil {
-		log.Fatal(err)
+		// The cluster predates `discovery.k8s.io/v1` (Kubernetes v1.21). Fall back to Endpoints.
+		//
+		// NOTE: We can use the same watch opts here because the `Endpoints` object will have the
+		// same name and be in the same namespace.
+		endpointEvents, err = watch.Forever("v1", "Endpoints", watch.ThisObject(namespace, name))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if withEvents {
+		if serviceEvents, err = followKubeEvents(namespace, serviceEvents); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	writer := uilive.New()
-	writer.RefreshInterval = time.Minute * 1
-	writer.Start()      // Start listening for updates, render.
-	defer writer.Stop() // Flush buffers, stop rendering.
+	table := map[string][]k8sWatch.Event{}
+	kubeEvents := trace.NewEventLog()
 
 	// Initial message.
-	fmt.Fprintln(writer, color.New(color.FgCyan, color.Bold).Sprintf("Waiting for Service '%s/%s'",
-		namespace, name))
-	writer.Flush()
-
-	table := map[string][]k8sWatch.Event{}
+	render(renderer, trace.NewService(namespace, name, table))
+	slices := map[string]k8sWatch.Event{} // EndpointSlice name -> EndpointSlice
 
 	for {
 		select {
 		case e := <-serviceEvents:
+			if o := e.Object.(*unstructured.Unstructured); trace.IsEvent(o) {
+				kubeEvents.Observe(o)
+				break
+			}
 			if e.Type == k8sWatch.Deleted {
 				o := e.Object.(*unstructured.Unstructured)
 				delete(o.Object, "spec")
 				delete(o.Object, "status")
 			}
-			table[v1Service] = []k8sWatch.Event{e}
+			table[trace.ServiceKey] = []k8sWatch.Event{e}
 		case e := <-endpointEvents:
 			if e.Type == k8sWatch.Deleted {
 				o := e.Object.(*unstructured.Unstructured)
@@ -95,19 +144,41 @@ func traceService(namespace, name string) {
 				delete(o.Object, "status")
 				delete(o.Object, "subsets")
 			}
-			table[v1Endpoints] = []k8sWatch.Event{e}
+			table[trace.EndpointsKey] = []k8sWatch.Event{e}
+		case e := <-endpointSliceEvents:
+			o := e.Object.(*unstructured.Unstructured)
+			if e.Type == k8sWatch.Deleted {
+				delete(slices, o.GetName())
+			} else {
+				slices[o.GetName()] = e
+			}
+			table[trace.EndpointSliceKey] = []k8sWatch.Event{}
+			for _, sliceEvent := range slices {
+				table[trace.EndpointSliceKey] = append(table[trace.EndpointSliceKey], sliceEvent)
+			}
 		}
-		print.ServiceWatchTable(writer, table)
+		s := trace.NewService(namespace, name, table)
+		s.Events = kubeEvents.Recent(trace.MaxEvents)
+		render(renderer, s)
 	}
 }
 
-func traceDeployment(namespace, name string) {
+// traceDeployment traces the Deployment `namespace/name` forever, unless `summarize` is true, in
+// which case it returns a summary of the rollout as soon as it succeeds or fails.
+func traceDeployment(
+	renderer print.Renderer, namespace, name string, summarize bool,
+) *trace.RolloutSummary {
 	// API server should rewrite this to apps/v1beta2, apps/v1beta2, or apps/v1 as appropriate.
 	deploymentEvents, err := watch.Forever("apps/v1", "Deployment",
 		watch.ThisObject(namespace, name))
 	if err != nil {
 		log.Fatal(err)
 	}
+	if withEvents {
+		if deploymentEvents, err = followKubeEvents(namespace, deploymentEvents); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	replicaSetEvents, err := watch.Forever("apps/v1", "ReplicaSet",
 		watch.ObjectsOwnedBy(namespace, name))
@@ -120,29 +191,39 @@ func traceDeployment(namespace, name string) {
 		log.Fatal(err)
 	}
 
-	writer := uilive.New()
-	writer.RefreshInterval = time.Minute * 1
-	writer.Start()      // Start listening for updates, render.
-	defer writer.Stop() // Flush buffers, stop rendering.
+	var logs *pods.LogTailer
+	var logUpdates <-chan struct{}
+	if showLogs {
+		source, err := pods.NewLogSource()
+		if err != nil {
+			log.Fatal(err)
+		}
+		logs = pods.NewLogTailer(source, logLines)
+		logUpdates = logs.Updates()
+	}
 
-	// Initial message.
-	fmt.Fprintln(writer, color.New(color.FgCyan, color.Bold).Sprintf("Waiting for Deployment '%s/%s'",
-		namespace, name))
-	writer.Flush()
+	table := map[string][]k8sWatch.Event{}  // apiVersion/Kind -> []k8sWatch.Event
+	repSets := map[string]k8sWatch.Event{}  // Deployment name -> Pod
+	podTable := map[string]k8sWatch.Event{} // ReplicaSet name -> Pod
+	kubeEvents := trace.NewEventLog()
 
-	table := map[string][]k8sWatch.Event{} // apiVersion/Kind -> []k8sWatch.Event
-	repSets := map[string]k8sWatch.Event{} // Deployment name -> Pod
-	pods := map[string]k8sWatch.Event{}    // ReplicaSet name -> Pod
+	// Initial message.
+	render(renderer, trace.NewDeployment(namespace, name, table, kubeEvents, logs))
+	s := trace.NewRolloutSummary(namespace, name)
 
 	for {
 		select {
 		case e := <-deploymentEvents:
+			if o := e.Object.(*unstructured.Unstructured); trace.IsEvent(o) {
+				kubeEvents.Observe(o)
+				break
+			}
 			if e.Type == k8sWatch.Deleted {
 				o := e.Object.(*unstructured.Unstructured)
 				delete(o.Object, "spec")
 				delete(o.Object, "status")
 			}
-			table[deployment] = []k8sWatch.Event{e}
+			table[trace.DeploymentKey] = []k8sWatch.Event{e}
 		case e := <-replicaSetEvents:
 			o := e.Object.(*unstructured.Unstructured)
 			if e.Type == k8sWatch.Deleted {
@@ -150,23 +231,52 @@ func traceDeployment(namespace, name string) {
 			} else {
 				repSets[o.GetName()] = e
 			}
-			table[v1ReplicaSet] = []k8sWatch.Event{}
+			table[trace.ReplicaSetKey] = []k8sWatch.Event{}
 			for _, rsEvent := range repSets {
-				table[v1ReplicaSet] = append(table[v1ReplicaSet], rsEvent)
+				table[trace.ReplicaSetKey] = append(table[trace.ReplicaSetKey], rsEvent)
 			}
 		case e := <-podEvents:
 			o := e.Object.(*unstructured.Unstructured)
 			if e.Type == k8sWatch.Deleted {
-				delete(pods, o.GetName())
+				delete(podTable, o.GetName())
 			} else {
-				pods[o.GetName()] = e
+				podTable[o.GetName()] = e
+			}
+
+			table[trace.PodKey] = []k8sWatch.Event{}
+			for _, podEvent := range podTable {
+				table[trace.PodKey] = append(table[trace.PodKey], podEvent)
 			}
+		case <-logUpdates:
+			// Log tails are fetched in the background; show those fetched since.
+		}
+		d := trace.NewDeployment(namespace, name, table, kubeEvents, logs)
+		d.Events = kubeEvents.Recent(trace.MaxEvents)
+		render(renderer, d)
 
-			table[v1Pod] = []k8sWatch.Event{}
-			for _, podEvent := range pods {
-				table[v1Pod] = append(table[v1Pod], podEvent)
+		if summarize {
+			s.Observe(time.Now(), d)
+			if s.Done() {
+				return s
 			}
 		}
-		print.DeploymentWatchTable(writer, table)
+	}
+}
+
+// newRenderer creates a Renderer for the output format `format` that writes to `out`.
+func newRenderer(format string, out *os.File) (print.Renderer, error) {
+	switch format {
+	case "text":
+		return print.NewRenderer(out), nil
+	case "json":
+		return print.NewStructuredRenderer(out), nil
+	default:
+		return nil, fmt.Errorf("Unknown output format '%s'; must be one of: text, json", format)
+	}
+}
+
+func render(renderer print.Renderer, m trace.Model) {
+	if err := renderer.Render(m); err != nil {
+		log.Fatal(err)
 	}
 }
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/pulumi/kubespy/print"
+	"github.com/pulumi/kubespy/trace"
+	"github.com/pulumi/kubespy/watch"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	k8sWatch "k8s.io/apimachinery/pkg/watch"
+)
+
+func init() {
+	treeCmd.Flags().StringVarP(&output, "output", "o", "text",
+		"Output format. One of: text, json (one JSON document per line, each time the tree changes)")
+	rootCmd.AddCommand(treeCmd)
+}
+
+var treeCmd = &cobra.Command{
+	Use:   "tree <type> [<namespace>/]<name>",
+	Short: "Displays a live tree of an API object and every object it owns",
+	Long: `Displays a live tree of an API object and every object it owns, directly or transitively,
+as determined by '.metadata.ownerReferences'. <type> is any resource type the API server knows
+about, e.g., 'deploy', 'deployments', or 'deployments.apps'.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		namespace, name, err := parseObjID(args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		apiVersion, kind, err := watch.ResolveType(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		renderer, err := newRenderer(output, os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer renderer.Close()
+
+		traceTree(renderer, apiVersion, kind, namespace, name)
+	},
+}
+
+func traceTree(renderer print.Renderer, apiVersion, kind, namespace, name string) {
+	rootEvents, err := watch.Forever(apiVersion, kind, watch.ThisObject(namespace, name))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	objectEvents, err := watch.AllNamespacedKinds(namespace)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var root *unstructured.Unstructured
+	objects := map[types.UID]*unstructured.Unstructured{}
+
+	// Initial message.
+	render(renderer, trace.NewTree(apiVersion, kind, namespace, name, root, objects))
+
+	// Re-render periodically even if nothing changes, so that ages stay current.
+	var tick <-chan time.Time
+	if print.IsLive(renderer) {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case e := <-rootEvents:
+			if e.Type == k8sWatch.Deleted {
+				root = nil
+			} else {
+				root = e.Object.(*unstructured.Unstructured)
+			}
+		case e := <-objectEvents:
+			o := e.Object.(*unstructured.Unstructured)
+			if e.Type == k8sWatch.Deleted {
+				delete(objects, o.GetUID())
+			} else {
+				objects[o.GetUID()] = o
+			}
+		case <-tick:
+		}
+
+		render(renderer, trace.NewTree(apiVersion, kind, namespace, name, root, objects))
+	}
+}
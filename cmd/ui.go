@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/pulumi/kubespy/ui"
+	"github.com/pulumi/kubespy/watch"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(uiCmd)
+}
+
+var uiCmd = &cobra.Command{
+	Use:   "ui <type> [<namespace>/]<name>",
+	Short: "Interactively explores an API object, the objects it owns, and their changes",
+	Long: `Displays a full-screen, interactive tree of an API object and every object it owns, as in
+'kubespy tree'. Selecting an object shows the live history of its changes (as in 'kubespy changes')
+or of its status (as in 'kubespy status'). Press 'p' to pause, '/' to search, 'y' to copy the
+selected object's YAML to the clipboard, and 'q' to quit.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		namespace, name, err := parseObjID(args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		apiVersion, kind, err := watch.ResolveType(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		rootEvents, err := watch.Forever(apiVersion, kind, watch.ThisObject(namespace, name))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		objectEvents, err := watch.AllNamespacedKinds(namespace)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := ui.Run(apiVersion, kind, namespace, name, rootEvents, objectEvents); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
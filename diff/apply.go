@@ -0,0 +1,106 @@
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Apply applies the JSON Patch `ops`, as computed by Patch, to `o` in place, and returns the result.
+// Only the operations Patch produces (`add`, `remove`, and `replace`) are supported.
+func Apply(o map[string]interface{}, ops []Operation) (map[string]interface{}, error) {
+	var doc interface{} = o
+	for _, op := range ops {
+		tokens, err := pointerTokens(op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = applyAt(doc, tokens, op); err != nil {
+			return nil, fmt.Errorf("Unable to %s '%s': %v", op.Op, op.Path, err)
+		}
+	}
+	result, isMap := doc.(map[string]interface{})
+	if !isMap {
+		return nil, fmt.Errorf("Patch did not produce an object")
+	}
+	return result, nil
+}
+
+// pointerTokens splits an RFC 6901 JSON Pointer into its unescaped reference tokens.
+func pointerTokens(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	} else if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("Invalid JSON Pointer '%s'", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// applyAt applies `op` to the value at `tokens` within `v`, returning the new value of `v`.
+func applyAt(v interface{}, tokens []string, op Operation) (interface{}, error) {
+	if len(tokens) == 0 {
+		if op.Op == "remove" {
+			return nil, nil
+		}
+		return op.Value, nil
+	}
+	token, last := tokens[0], len(tokens) == 1
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		child, exists := v[token]
+		if last {
+			switch op.Op {
+			case "remove":
+				if !exists {
+					return nil, fmt.Errorf("no field '%s'", token)
+				}
+				delete(v, token)
+			case "add", "replace":
+				v[token] = op.Value
+			default:
+				return nil, fmt.Errorf("unsupported operation")
+			}
+			return v, nil
+		}
+		if !exists {
+			return nil, fmt.Errorf("no field '%s'", token)
+		}
+		updated, err := applyAt(child, tokens[1:], op)
+		if err != nil {
+			return nil, err
+		}
+		v[token] = updated
+		return v, nil
+	case []interface{}:
+		index, err := strconv.Atoi(token)
+		if err != nil || index < 0 || index > len(v) || (index == len(v) && !(last && op.Op == "add")) {
+			return nil, fmt.Errorf("no element '%s'", token)
+		}
+		if last {
+			switch op.Op {
+			case "remove":
+				return append(v[:index:index], v[index+1:]...), nil
+			case "add":
+				return append(v[:index:index], append([]interface{}{op.Value}, v[index:]...)...), nil
+			case "replace":
+				v[index] = op.Value
+				return v, nil
+			default:
+				return nil, fmt.Errorf("unsupported operation")
+			}
+		}
+		updated, err := applyAt(v[index], tokens[1:], op)
+		if err != nil {
+			return nil, err
+		}
+		v[index] = updated
+		return v, nil
+	default:
+		return nil, fmt.Errorf("not an object or array")
+	}
+}
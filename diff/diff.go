@@ -0,0 +1,128 @@
+// Package diff computes the differences between two states of a Kubernetes object, in a number of
+// formats: lists of the changed fields with list elements matched up by key, gojsondiff's
+// human-readable ASCII and delta formats, RFC 6902 JSON Patches, RFC 7386 JSON Merge Patches, and
+// unified diffs of the objects' YAML.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/yudai/gojsondiff"
+	"github.com/yudai/gojsondiff/formatter"
+)
+
+// Format is a format in which to render the differences between two objects.
+type Format string
+
+const (
+	// KeyedYAML lists each changed field under its path, with list elements matched up and named by
+	// their keys (e.g., `.spec.containers[name=nginx].image`), followed by the YAML of its old and
+	// new values. See Keyed.
+	KeyedYAML Format = "keyed"
+	// ASCII is gojsondiff's annotated JSON, with added and removed lines marked `+` and `-`.
+	ASCII Format = "ascii"
+	// JSONPatch is an RFC 6902 JSON Patch that transforms the old object into the new one.
+	JSONPatch Format = "jsonpatch"
+	// MergePatch is an RFC 7386 JSON Merge Patch that transforms the old object into the new one.
+	MergePatch Format = "mergepatch"
+	// UnifiedYAML is a unified diff, with context lines, of the objects' YAML.
+	UnifiedYAML Format = "unified-yaml"
+	// DeltaJSON is gojsondiff's (and jsondiffpatch's) JSON delta format.
+	DeltaJSON Format = "delta-json"
+)
+
+// Formats are all of the supported formats.
+var Formats = []Format{KeyedYAML, ASCII, JSONPatch, MergePatch, UnifiedYAML, DeltaJSON}
+
+// ParseFormat parses the name of a Format.
+func ParseFormat(name string) (Format, error) {
+	names := []string{}
+	for _, f := range Formats {
+		if string(f) == name {
+			return f, nil
+		}
+		names = append(names, string(f))
+	}
+	return "", fmt.Errorf("Unknown diff format '%s'; must be one of: %s", name,
+		strings.Join(names, ", "))
+}
+
+// Colors are the colors the formats meant for people render changes in. A nil color leaves its text
+// uncolored, so the zero Colors renders changes without color.
+type Colors struct {
+	Added   *color.Color
+	Removed *color.Color
+
+	// Location colors where changes are, i.e., the headers of the hunks of a unified diff and the
+	// paths of a keyed one, and File the names of the files a unified diff compares.
+	Location *color.Color
+	File     *color.Color
+}
+
+// enabled is true if any text is colored.
+func (c Colors) enabled() bool {
+	return c != Colors{}
+}
+
+// style colors `s` with `c`, if any.
+func style(c *color.Color, s string) string {
+	if c == nil {
+		return s
+	}
+	return c.Sprint(s)
+}
+
+// Compute renders the changes from `before` to `after` in `format`. Formats meant for people are
+// colored with `colors`; those meant for machines never are. `modified` is false if the
+// objects are identical, in which case `text` is empty. KeyedYAML matches up the elements of
+// well-known lists by StaticSchema; use Keyed to match them up by another Schema.
+func Compute(
+	before, after map[string]interface{}, format Format, colors Colors,
+) (text string, modified bool, err error) {
+	switch format {
+	case KeyedYAML:
+		return Keyed(before, after, StaticSchema, Path{}, colors)
+	case ASCII, DeltaJSON:
+		d := gojsondiff.New().CompareObjects(before, after)
+		if !d.Modified() {
+			return "", false, nil
+		}
+		if format == DeltaJSON {
+			text, err = formatter.NewDeltaFormatter().Format(d)
+		} else {
+			fcfg := formatter.AsciiFormatterConfig{Coloring: colors.enabled()}
+			text, err = formatter.NewAsciiFormatter(before, fcfg).Format(d)
+		}
+		if err != nil {
+			return "", false, err
+		}
+		return text, true, nil
+	case JSONPatch:
+		ops := Patch(before, after)
+		if len(ops) == 0 {
+			return "", false, nil
+		}
+		return marshal(ops)
+	case MergePatch:
+		patch := MergePatchOf(before, after)
+		if len(patch) == 0 {
+			return "", false, nil
+		}
+		return marshal(patch)
+	case UnifiedYAML:
+		return Unified(before, after, defaultContext, colors)
+	default:
+		return "", false, fmt.Errorf("Unknown diff format '%s'", format)
+	}
+}
+
+func marshal(v interface{}) (string, bool, error) {
+	j, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", false, err
+	}
+	return string(j) + "\n", true, nil
+}
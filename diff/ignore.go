@@ -0,0 +1,29 @@
+package diff
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DefaultIgnores are the fields that change constantly without telling anyone anything, e.g., the
+// heartbeats of Node conditions.
+var DefaultIgnores = []string{
+	"metadata.resourceVersion",
+	"metadata.managedFields",
+	"metadata.generation",
+	"status.conditions[*].lastHeartbeatTime",
+	"status.conditions[*].lastProbeTime",
+}
+
+// Without returns a copy of `o` with every field matching any of `ignores` removed. `o` is not
+// modified.
+func Without(o map[string]interface{}, ignores []Path) map[string]interface{} {
+	if len(ignores) == 0 {
+		return o
+	}
+
+	stripped := runtime.DeepCopyJSON(o)
+	for _, p := range ignores {
+		p.Remove(stripped)
+	}
+	return stripped
+}
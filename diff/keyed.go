@@ -0,0 +1,55 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"sigs.k8s.io/yaml"
+)
+
+// Keyed renders the changes from `before` to `after` to the part of the object at `within`, as
+// computed by KeyedChanges, so that list elements are matched up by the keys `schema` has for them.
+// Each change is headed by its path, e.g., `.spec.template.spec.containers[name=nginx].image`, and
+// followed by the YAML of the old value, marked `-`, and of the new one, marked `+`. `modified` is
+// false if nothing at `within` changed, in which case `text` is empty. Changes are colored with
+// `colors`.
+func Keyed(
+	before, after map[string]interface{}, schema Schema, within Path, colors Colors,
+) (text string, modified bool, err error) {
+	var b strings.Builder
+	value := func(mark string, c *color.Color, v interface{}) error {
+		y, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		for _, line := range splitLines(string(y)) {
+			fmt.Fprintln(&b, style(c, mark+" "+line))
+		}
+		return nil
+	}
+
+	for _, c := range KeyedChanges(before, after, schema) {
+		// A change to a field that contains `within` (e.g., the whole list it is an element of) is
+		// shown whole.
+		if !c.Path.HasPrefix(within) && !within.HasPrefix(c.Path) {
+			continue
+		}
+
+		fmt.Fprintln(&b, style(colors.Location, c.Path.String()))
+		if c.Op != "add" {
+			if err := value("-", colors.Removed, c.Old); err != nil {
+				return "", false, err
+			}
+		}
+		if c.Op != "remove" {
+			if err := value("+", colors.Added, c.Value); err != nil {
+				return "", false, err
+			}
+		}
+	}
+	if b.Len() == 0 {
+		return "", false, nil
+	}
+	return b.String(), true, nil
+}
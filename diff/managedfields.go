@@ -0,0 +1,190 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pulumi/pulumi-kubernetes/provider/v4/pkg/openapi"
+)
+
+// managedFieldsPath is never attributed: every write changes it, and nothing owns it.
+var managedFieldsPath = Path{{key: "metadata"}, {key: "managedFields"}}
+
+// Manager is a field manager that owns some of an object's fields, as recorded in the object's
+// `.metadata.managedFields`, e.g., `kubectl-client-side-apply` or `kube-controller-manager`.
+type Manager struct {
+	Name string `json:"manager"`
+	// Operation is `Apply` (server-side apply) or `Update` (any other write).
+	Operation   string `json:"operation"`
+	Subresource string `json:"subresource,omitempty"`
+}
+
+// String renders the manager as, e.g., `kubectl-client-side-apply (Update)`.
+func (m Manager) String() string {
+	if m.Subresource != "" {
+		return m.Name + " (" + m.Operation + " " + m.Subresource + ")"
+	}
+	return m.Name + " (" + m.Operation + ")"
+}
+
+// Attribution names the field managers responsible for a single change.
+type Attribution struct {
+	Change
+
+	// Managers own the changed field after the change or, if the field was removed, owned it
+	// before.
+	Managers []Manager
+
+	// Previous are the managers that owned the field before it was changed, if they differ from
+	// Managers. Two controllers fighting over a field show up as its ownership flipping back and
+	// forth.
+	Previous []Manager
+}
+
+// Attribute attributes each change from `before` to `after` to the field managers that made it, by
+// decoding the `.metadata.managedFields` of each. Fields matching `ignores` are not attributed.
+// Elements of lists that `schema` has keys for are matched by key; `schema` may be nil.
+func Attribute(before, after map[string]interface{}, ignores []Path, schema Schema) []Attribution {
+	attributions := []Attribution{}
+	for _, c := range KeyedChanges(Without(before, ignores), Without(after, ignores), schema) {
+		if c.Path.HasPrefix(managedFieldsPath) {
+			continue
+		}
+
+		a := Attribution{Change: c}
+		switch c.Op {
+		case "remove":
+			a.Managers = owners(before, c.Path)
+		case "add":
+			a.Managers = owners(after, c.Path)
+		default:
+			a.Managers = owners(after, c.Path)
+			// A field no one owned before (e.g., one set by defaulting) didn't change owner.
+			previous := owners(before, c.Path)
+			if len(previous) > 0 && !reflect.DeepEqual(previous, a.Managers) {
+				a.Previous = previous
+			}
+		}
+		attributions = append(attributions, a)
+	}
+	return attributions
+}
+
+// owners returns the field managers of `o` that own the field at `p`, or any field within it.
+func owners(o map[string]interface{}, p Path) []Manager {
+	managers := []Manager{}
+
+	managedFieldsI, _ := openapi.Pluck(o, "metadata", "managedFields")
+	managedFields, _ := managedFieldsI.([]interface{})
+	for _, entryI := range managedFields {
+		entry, isMap := entryI.(map[string]interface{})
+		if !isMap || entry["fieldsType"] != "FieldsV1" {
+			continue
+		}
+		fields, isMap := entry["fieldsV1"].(map[string]interface{})
+		if !isMap || !ownsField(fields, o, p) {
+			continue
+		}
+
+		m := Manager{}
+		m.Name, _ = entry["manager"].(string)
+		m.Operation, _ = entry["operation"].(string)
+		m.Subresource, _ = entry["subresource"].(string)
+		managers = append(managers, m)
+	}
+	return managers
+}
+
+// ownsField walks the field set `fields` (in the `FieldsV1` format) alongside the value `v`, and
+// reports whether the set contains the field at `p`.
+//
+// Fields of objects are keyed `f:<name>`. Elements of lists are keyed `k:<merge keys>` if the list
+// is a map keyed on some of its elements' fields, `v:<value>` if it is a set, and `i:<index>`
+// otherwise; the key of an element is found by matching it against the element of `v`, so that no
+// schema is needed. An element of `p` that has a key (e.g., `[name=nginx]`) is found in `v` by that
+// key rather than its index, which may differ between the states of an object.
+func ownsField(fields map[string]interface{}, v interface{}, p Path) bool {
+	if len(p) == 0 {
+		return true
+	}
+	seg := p[0]
+
+	var child interface{}
+	var hasChild bool
+	if !seg.isIndex {
+		obj, _ := v.(map[string]interface{})
+		child, hasChild = fields["f:"+seg.key]
+		v = obj[seg.key]
+	} else {
+		list, _ := v.([]interface{})
+		index := seg.index
+		if seg.match != "" {
+			index = -1
+			for i, elem := range list {
+				if seg.selects(i, elem) {
+					index = i
+					break
+				}
+			}
+		}
+		if index < 0 || index >= len(list) {
+			return false
+		}
+		elem := list[index]
+		for key, value := range fields {
+			if elementMatches(key, index, elem) {
+				child, hasChild = value, true
+				break
+			}
+		}
+		v = elem
+	}
+	if !hasChild {
+		return false
+	}
+
+	childFields, _ := child.(map[string]interface{})
+	return ownsField(childFields, v, p[1:])
+}
+
+// elementMatches reports whether `key` in a field set identifies the list element `elem`, at
+// `index`.
+func elementMatches(key string, index int, elem interface{}) bool {
+	switch {
+	case strings.HasPrefix(key, "i:"):
+		return key == "i:"+strconv.Itoa(index)
+	case strings.HasPrefix(key, "v:"):
+		var value interface{}
+		if err := json.Unmarshal([]byte(key[2:]), &value); err != nil {
+			return false
+		}
+		return jsonEqual(value, elem)
+	case strings.HasPrefix(key, "k:"):
+		var mergeKeys map[string]interface{}
+		if err := json.Unmarshal([]byte(key[2:]), &mergeKeys); err != nil {
+			return false
+		}
+		obj, isMap := elem.(map[string]interface{})
+		if !isMap {
+			return false
+		}
+		for name, value := range mergeKeys {
+			if !jsonEqual(value, obj[name]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// jsonEqual compares values by their JSON, since numbers decoded from field sets are `float64`s,
+// but those of objects from the API server are `int64`s.
+func jsonEqual(a, b interface{}) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aJSON) == string(bJSON)
+}
@@ -0,0 +1,199 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// Operation is a single operation of an RFC 6902 JSON Patch.
+type Operation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// MarshalJSON omits the value of `remove` operations, which have none. Other operations always
+// have one, even if it is `null`.
+func (o Operation) MarshalJSON() ([]byte, error) {
+	if o.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{o.Op, o.Path})
+	}
+	type plainOperation Operation
+	return json.Marshal(plainOperation(o))
+}
+
+// Patch computes an RFC 6902 JSON Patch that transforms `before` into `after`. Object keys are
+// visited in sorted order, so the same objects always produce the same patch.
+func Patch(before, after map[string]interface{}) []Operation {
+	ops := []Operation{}
+	for _, c := range Changes(before, after) {
+		ops = append(ops, Operation{Op: c.Op, Path: c.Path.Pointer(), Value: c.Value})
+	}
+	return ops
+}
+
+// Change is a single change to a field of an object.
+type Change struct {
+	// Op is `add`, `remove`, or `replace`, as in a JSON Patch.
+	Op    string
+	Path  Path
+	Value interface{}
+
+	// Old is the value that was replaced or removed, if any. It is not part of a JSON Patch.
+	Old interface{}
+}
+
+// Changes computes the changes that transform `before` into `after`, in the order they would be
+// applied by a JSON Patch. Object keys are visited in sorted order.
+func Changes(before, after map[string]interface{}) []Change {
+	changes := []Change{}
+	changeValue(&changes, nil, Path{}, before, after)
+	return changes
+}
+
+// KeyedChanges is like Changes, but matches up the elements of lists that `schema` has keys for by
+// those keys, rather than by index, so that adding, removing, or moving one element is a single
+// change (or none). The paths of such elements carry their keys, e.g.,
+// `spec.containers[name=nginx].image`; their indices are those in `after`, or in `before` if the
+// element was removed. The changes are not a valid JSON Patch.
+func KeyedChanges(before, after map[string]interface{}, schema Schema) []Change {
+	changes := []Change{}
+	changeValue(&changes, schema, Path{}, before, after)
+	return changes
+}
+
+func changeValue(changes *[]Change, schema Schema, path Path, before, after interface{}) {
+	if reflect.DeepEqual(before, after) {
+		return
+	}
+
+	switch before := before.(type) {
+	case map[string]interface{}:
+		if after, isMap := after.(map[string]interface{}); isMap {
+			changeObject(changes, schema, path, before, after)
+			return
+		}
+	case []interface{}:
+		if after, isSlice := after.([]interface{}); isSlice {
+			changeArray(changes, schema, path, before, after)
+			return
+		}
+	}
+	*changes = append(*changes, Change{Op: "replace", Path: path, Value: after, Old: before})
+}
+
+func changeObject(
+	changes *[]Change, schema Schema, path Path, before, after map[string]interface{},
+) {
+	for _, key := range sortedKeys(before) {
+		if _, exists := after[key]; !exists {
+			*changes = append(*changes, Change{Op: "remove", Path: path.Key(key), Old: before[key]})
+		}
+	}
+	for _, key := range sortedKeys(after) {
+		if value, exists := before[key]; exists {
+			changeValue(changes, schema, path.Key(key), value, after[key])
+		} else {
+			*changes = append(*changes, Change{Op: "add", Path: path.Key(key), Value: after[key]})
+		}
+	}
+}
+
+// changeArray changes arrays element by element, appending or truncating as needed. Elements are
+// removed from the end first, so that the indices of later changes stay valid.
+func changeArray(changes *[]Change, schema Schema, path Path, before, after []interface{}) {
+	if schema != nil {
+		if keys := schema.ListKeys(path); len(keys) > 0 {
+			beforeKeys, beforeOK := keysOf(before, keys)
+			afterKeys, afterOK := keysOf(after, keys)
+			if beforeOK && afterOK {
+				changeKeyedArray(changes, schema, path, before, after, beforeKeys, afterKeys)
+				return
+			}
+		}
+	}
+
+	common := len(before)
+	if len(after) < common {
+		common = len(after)
+	}
+
+	for i := len(before) - 1; i >= common; i-- {
+		*changes = append(*changes, Change{Op: "remove", Path: path.Index(i), Old: before[i]})
+	}
+	for i := 0; i < common; i++ {
+		changeValue(changes, schema, path.Index(i), before[i], after[i])
+	}
+	for i := common; i < len(after); i++ {
+		*changes = append(*changes, Change{Op: "add", Path: path.Index(i), Value: after[i]})
+	}
+}
+
+// changeKeyedArray matches up the elements of arrays by their keys. Elements that only moved are
+// not changed.
+func changeKeyedArray(
+	changes *[]Change, schema Schema, path Path, before, after []interface{},
+	beforeKeys, afterKeys []string,
+) {
+	beforeIndex := map[string]int{}
+	for i, key := range beforeKeys {
+		beforeIndex[key] = i
+	}
+	afterIndex := map[string]int{}
+	for i, key := range afterKeys {
+		afterIndex[key] = i
+	}
+
+	for i, key := range beforeKeys {
+		if _, exists := afterIndex[key]; !exists {
+			*changes = append(*changes,
+				Change{Op: "remove", Path: path.Element(i, key), Old: before[i]})
+		}
+	}
+	for i, key := range afterKeys {
+		if j, exists := beforeIndex[key]; exists {
+			changeValue(changes, schema, path.Element(i, key), before[j], after[i])
+		} else {
+			*changes = append(*changes, Change{Op: "add", Path: path.Element(i, key), Value: after[i]})
+		}
+	}
+}
+
+// MergePatchOf computes an RFC 7386 JSON Merge Patch that transforms `before` into `after`. Merge
+// patches can't express changes to individual array elements, so changed arrays are replaced whole.
+func MergePatchOf(before, after map[string]interface{}) map[string]interface{} {
+	patch := map[string]interface{}{}
+	for key := range before {
+		if _, exists := after[key]; !exists {
+			patch[key] = nil
+		}
+	}
+	for key, value := range after {
+		old, exists := before[key]
+		if exists && reflect.DeepEqual(old, value) {
+			continue
+		}
+
+		oldMap, oldIsMap := old.(map[string]interface{})
+		newMap, newIsMap := value.(map[string]interface{})
+		if exists && oldIsMap && newIsMap {
+			patch[key] = MergePatchOf(oldMap, newMap)
+		} else {
+			patch[key] = value
+		}
+	}
+	return patch
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
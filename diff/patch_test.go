@@ -0,0 +1,154 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// object parses the JSON object `s`, failing the test if it is not one.
+func object(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	o := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(s), &o); err != nil {
+		t.Fatalf("Invalid test object %s: %v", s, err)
+	}
+	return o
+}
+
+var patchTests = []struct {
+	name          string
+	before, after string
+}{
+	{"unchanged", `{"a": 1}`, `{"a": 1}`},
+	{"empty", `{}`, `{}`},
+	{"field added", `{"a": 1}`, `{"a": 1, "b": {"c": 2}}`},
+	{"field removed", `{"a": 1, "b": {"c": 2}}`, `{"a": 1}`},
+	{"field replaced", `{"a": 1}`, `{"a": "one"}`},
+	{"null values", `{"a": null}`, `{"a": 1, "b": null}`},
+	{"nested field removed", `{"a": {"b": {"c": 1, "d": 2}}}`, `{"a": {"b": {"c": 1}}}`},
+	{"object replaced by array", `{"a": {"b": 1}}`, `{"a": [1]}`},
+	{"elements appended", `{"a": [1]}`, `{"a": [1, 2, 3]}`},
+	{"elements truncated", `{"a": [1, 2, 3]}`, `{"a": [1]}`},
+	{"elements changed", `{"a": [{"b": 1}, 2]}`, `{"a": [{"b": 2}, 3]}`},
+	{"array emptied", `{"a": [1, 2]}`, `{"a": []}`},
+	{"keys that need escaping", `{"a/b": 1, "c~d": {"e": 1}}`, `{"a/b": 2, "c~d": {}}`},
+	{
+		"container image updated",
+		`{"spec": {"containers": [{"name": "app", "image": "nginx:1.14"}, {"name": "log"}]}}`,
+		`{"spec": {"containers": [{"name": "app", "image": "nginx:1.15"}]}}`,
+	},
+}
+
+func TestPatchRoundTrip(t *testing.T) {
+	for _, test := range patchTests {
+		ops := Patch(object(t, test.before), object(t, test.after))
+		got, err := Apply(object(t, test.before), ops)
+		if err != nil {
+			t.Errorf("%s: Apply(%s, %v) failed: %v", test.name, test.before, ops, err)
+			continue
+		}
+		if want := object(t, test.after); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Apply(%s, %v) = %v, want %v", test.name, test.before, ops, got, want)
+		}
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		op   Operation
+	}{
+		{"missing field", Operation{Op: "remove", Path: "/b"}},
+		{"missing parent", Operation{Op: "add", Path: "/b/c", Value: 1}},
+		{"index out of range", Operation{Op: "replace", Path: "/a/2", Value: 1}},
+		{"index not a number", Operation{Op: "replace", Path: "/a/x", Value: 1}},
+		{"not an object or array", Operation{Op: "add", Path: "/c/d", Value: 1}},
+		{"not a pointer", Operation{Op: "add", Path: "a", Value: 1}},
+		{"unsupported operation", Operation{Op: "move", Path: "/c"}},
+	}
+	for _, test := range tests {
+		o := object(t, `{"a": [1, 2], "c": 3}`)
+		if _, err := Apply(o, []Operation{test.op}); err == nil {
+			t.Errorf("%s: Apply(%v) succeeded, want an error", test.name, test.op)
+		}
+	}
+}
+
+func TestMergePatchOf(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		want          string
+	}{
+		{"unchanged", `{"a": {"b": 1}}`, `{"a": {"b": 1}}`, `{}`},
+		{"field removed", `{"a": 1, "b": 2}`, `{"a": 1}`, `{"b": null}`},
+		{"nested field removed", `{"a": {"b": {"c": 1, "d": 2}}}`, `{"a": {"b": {"c": 1}}}`,
+			`{"a": {"b": {"d": null}}}`},
+		{"object removed", `{"a": {"b": {"c": 1}}, "d": 1}`, `{"d": 1}`, `{"a": null}`},
+		{"nested object emptied", `{"a": {"b": 1, "c": 2}}`, `{"a": {}}`,
+			`{"a": {"b": null, "c": null}}`},
+		{"removed alongside changed", `{"a": {"b": 1, "c": 2}}`, `{"a": {"b": 3}}`,
+			`{"a": {"b": 3, "c": null}}`},
+		{"array replaced whole", `{"a": [1, 2, 3]}`, `{"a": [1, 3]}`, `{"a": [1, 3]}`},
+		{"object replaced by value", `{"a": {"b": 1}}`, `{"a": 1}`, `{"a": 1}`},
+	}
+	for _, test := range tests {
+		got := MergePatchOf(object(t, test.before), object(t, test.after))
+		if want := object(t, test.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: MergePatchOf(%s, %s) = %v, want %v", test.name, test.before, test.after,
+				got, want)
+		}
+	}
+}
+
+func TestKeyedChanges(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		want          []string
+	}{
+		{
+			"container removed",
+			`{"spec": {"containers": [{"name": "log"}, {"name": "app", "image": "nginx:1.14"}]}}`,
+			`{"spec": {"containers": [{"name": "app", "image": "nginx:1.15"}]}}`,
+			[]string{
+				"remove .spec.containers[name=log]",
+				"replace .spec.containers[name=app].image",
+			},
+		},
+		{
+			"containers reordered",
+			`{"spec": {"containers": [{"name": "log"}, {"name": "app"}]}}`,
+			`{"spec": {"containers": [{"name": "app"}, {"name": "log"}]}}`,
+			[]string{},
+		},
+		{
+			"unkeyed list",
+			`{"spec": {"args": ["a", "b"]}}`,
+			`{"spec": {"args": ["b"]}}`,
+			[]string{"remove .spec.args[1]", "replace .spec.args[0]"},
+		},
+		{
+			// Elements without their keys can't be matched up, so they are compared by index.
+			"missing keys",
+			`{"spec": {"containers": [{"image": "a"}, {"name": "app"}]}}`,
+			`{"spec": {"containers": [{"name": "app"}]}}`,
+			[]string{
+				"remove .spec.containers[1]",
+				"remove .spec.containers[0].image",
+				"add .spec.containers[0].name",
+			},
+		},
+	}
+	for _, test := range tests {
+		got := []string{}
+		for _, c := range KeyedChanges(object(t, test.before), object(t, test.after), StaticSchema) {
+			got = append(got, c.Op+" "+c.Path.String())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: KeyedChanges(%s, %s) = %q, want %q", test.name, test.before, test.after,
+				got, test.want)
+		}
+	}
+}
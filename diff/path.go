@@ -0,0 +1,295 @@
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Path identifies zero or more fields of an object, e.g., `metadata.managedFields` or
+// `status.conditions[*].lastProbeTime`. Keys are separated by dots; keys that contain dots can be
+// quoted in brackets, as in `metadata.annotations["kubernetes.io/change-cause"]`. `[N]` selects an
+// element of an array, `[name=nginx]` selects the elements of an array whose `name` is `nginx`
+// (several keys are separated by commas, as in `[containerPort=80,protocol=TCP]`), and `*` or `[*]`
+// selects every key of an object or element of an array.
+type Path []segment
+
+type segment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+
+	// match is the key of a list element, e.g., `name=nginx`. An element is matched by its key
+	// alone, unless the segment also has an index.
+	match string
+}
+
+// ParsePath parses a Path. A leading dot is optional, and so is the `$` or `{...}` of a JSONPath
+// expression, so that `.status`, `status`, `$.status`, and `{.status}` are all the same path.
+func ParsePath(s string) (Path, error) {
+	p := Path{}
+	rest := strings.TrimSpace(s)
+	if strings.HasPrefix(rest, "{") && strings.HasSuffix(rest, "}") {
+		rest = rest[1 : len(rest)-1]
+	}
+	rest = strings.TrimPrefix(strings.TrimPrefix(rest, "$"), ".")
+	if rest == "" {
+		return p, nil
+	}
+
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("Invalid path '%s': unterminated '['", s)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+
+			if inner == "*" {
+				p = append(p, segment{wildcard: true})
+			} else if unquoted, err := strconv.Unquote(inner); err == nil {
+				p = append(p, segment{key: unquoted})
+			} else if len(inner) >= 2 && inner[0] == '\'' && inner[len(inner)-1] == '\'' {
+				p = append(p, segment{key: inner[1 : len(inner)-1]})
+			} else if index, err := strconv.Atoi(inner); err == nil && index >= 0 {
+				p = append(p, segment{index: index, isIndex: true})
+			} else if _, ok := matchKeys(inner); ok {
+				p = append(p, segment{match: inner})
+			} else {
+				return nil, fmt.Errorf(
+					"Invalid path '%s': '[%s]' must be an index, a quoted key, an element key like "+
+						"'name=nginx', or '*'", s, inner)
+			}
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			if rest == "" || rest[0] == '.' {
+				return nil, fmt.Errorf("Invalid path '%s': empty key", s)
+			}
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			rest = rest[end:]
+
+			if key == "*" {
+				p = append(p, segment{wildcard: true})
+			} else {
+				p = append(p, segment{key: key})
+			}
+		}
+	}
+	return p, nil
+}
+
+// ParsePaths parses a list of Paths.
+func ParsePaths(specs []string) ([]Path, error) {
+	paths := []Path{}
+	for _, spec := range specs {
+		p, err := ParsePath(spec)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+// String renders the path in the syntax ParsePath accepts.
+func (p Path) String() string {
+	var b strings.Builder
+	for _, seg := range p {
+		switch {
+		case seg.wildcard:
+			b.WriteString("[*]")
+		case seg.match != "":
+			fmt.Fprintf(&b, "[%s]", seg.match)
+		case seg.isIndex:
+			fmt.Fprintf(&b, "[%d]", seg.index)
+		case strings.ContainsAny(seg.key, ".[]") || seg.key == "*" || seg.key == "":
+			fmt.Fprintf(&b, "[%q]", seg.key)
+		default:
+			b.WriteString("." + seg.key)
+		}
+	}
+	if b.Len() == 0 {
+		return "."
+	}
+	return b.String()
+}
+
+// Key returns the path of the field `key` of the object at `p`.
+func (p Path) Key(key string) Path {
+	return append(p[:len(p):len(p)], segment{key: key})
+}
+
+// Index returns the path of the element `index` of the array at `p`.
+func (p Path) Index(index int) Path {
+	return append(p[:len(p):len(p)], segment{index: index, isIndex: true})
+}
+
+// Element returns the path of the element `index` of the array at `p`, whose key is `key` (e.g.,
+// `name=nginx`). The path is rendered with the key, which means more to people than the index.
+func (p Path) Element(index int, key string) Path {
+	return append(p[:len(p):len(p)], segment{index: index, isIndex: true, match: key})
+}
+
+// HasPrefix is true if `p` is `prefix`, or a field within it. Wildcards in `prefix` match any key
+// or index, and element keys in `prefix` match the elements of `p` with the same key.
+func (p Path) HasPrefix(prefix Path) bool {
+	if len(prefix) > len(p) {
+		return false
+	}
+	for i, seg := range prefix {
+		if !seg.matches(p[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether the segment of a concrete path `other` is one that `seg` selects.
+func (seg segment) matches(other segment) bool {
+	switch {
+	case seg.wildcard:
+		return true
+	case seg.match != "":
+		return other.match == seg.match
+	case seg.isIndex:
+		return other.isIndex && other.index == seg.index
+	default:
+		return !other.isIndex && other.match == "" && !other.wildcard && other.key == seg.key
+	}
+}
+
+// selects reports whether `seg` selects the element `elem` at `index` of a list.
+func (seg segment) selects(index int, elem interface{}) bool {
+	switch {
+	case seg.wildcard:
+		return true
+	case seg.match != "":
+		keys, _ := matchKeys(seg.match)
+		key, ok := keyOf(elem, keys)
+		return ok && key == seg.match
+	default:
+		return seg.isIndex && index == seg.index
+	}
+}
+
+// matchKeys returns the names of the fields in the element key `match`, e.g., `containerPort` and
+// `protocol` for `containerPort=80,protocol=TCP`.
+func matchKeys(match string) ([]string, bool) {
+	keys := []string{}
+	for _, part := range strings.Split(match, ",") {
+		eq := strings.Index(part, "=")
+		if eq <= 0 {
+			return nil, false
+		}
+		keys = append(keys, part[:eq])
+	}
+	return keys, true
+}
+
+// Pointer renders a path without wildcards as an RFC 6901 JSON Pointer.
+func (p Path) Pointer() string {
+	var b strings.Builder
+	for _, seg := range p {
+		b.WriteString("/")
+		switch {
+		case seg.wildcard:
+			b.WriteString("*")
+		case seg.isIndex:
+			b.WriteString(strconv.Itoa(seg.index))
+		default:
+			b.WriteString(strings.ReplaceAll(strings.ReplaceAll(seg.key, "~", "~0"), "/", "~1"))
+		}
+	}
+	return b.String()
+}
+
+// Select returns every field of `v` matching the path, keyed by its concrete path (i.e., with
+// wildcards replaced by the keys and indices they matched).
+func (p Path) Select(v interface{}) map[string]interface{} {
+	matches := map[string]interface{}{}
+	p.selectInto(matches, Path{}, v)
+	return matches
+}
+
+func (p Path) selectInto(matches map[string]interface{}, at Path, v interface{}) {
+	if len(p) == 0 {
+		matches[at.String()] = v
+		return
+	}
+	seg := p[0]
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if seg.isIndex || seg.match != "" {
+			return
+		}
+		for key, child := range v {
+			if seg.wildcard || key == seg.key {
+				p[1:].selectInto(matches, at.Key(key), child)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			if seg.selects(i, child) {
+				p[1:].selectInto(matches, at.Index(i), child)
+			}
+		}
+	}
+}
+
+// Subtree extracts the part of `o` at the path, so that it can be diffed on its own. If the path
+// identifies a single object, that object is returned. Otherwise (e.g., the path has wildcards, or
+// identifies a list or a scalar) the matches are returned keyed by their paths. If nothing matches,
+// the subtree is empty.
+func (p Path) Subtree(o map[string]interface{}) map[string]interface{} {
+	matches := p.Select(o)
+	if len(matches) == 1 {
+		if subtree, isMap := matches[p.String()].(map[string]interface{}); isMap {
+			return subtree
+		}
+	}
+	return matches
+}
+
+// Remove deletes every field matching the path from `v`, in place.
+func (p Path) Remove(v interface{}) {
+	if len(p) == 0 {
+		return
+	}
+	seg, last := p[0], len(p) == 1
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if seg.isIndex || seg.match != "" {
+			return
+		}
+		for key, child := range v {
+			if !seg.wildcard && key != seg.key {
+				continue
+			}
+			if last {
+				delete(v, key)
+			} else {
+				p[1:].Remove(child)
+			}
+		}
+	case []interface{}:
+		// Removing array elements would shift the indices of the rest, so only descend into them.
+		if last {
+			return
+		}
+		for i, child := range v {
+			if seg.selects(i, child) {
+				p[1:].Remove(child)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package diff
+
+import (
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"", "."},
+		{".", "."},
+		{"$", "."},
+		{"status", ".status"},
+		{".status", ".status"},
+		{"$.status", ".status"},
+		{"{.status}", ".status"},
+		{" metadata.managedFields ", ".metadata.managedFields"},
+		{"status.conditions[*].lastProbeTime", ".status.conditions[*].lastProbeTime"},
+		{"metadata.labels.*", ".metadata.labels[*]"},
+		{"spec.containers[0].image", ".spec.containers[0].image"},
+		{"spec.containers[name=nginx].image", ".spec.containers[name=nginx].image"},
+		{"spec.ports[port=80,protocol=TCP]", ".spec.ports[port=80,protocol=TCP]"},
+		{`metadata.annotations["kubernetes.io/change-cause"]`,
+			`.metadata.annotations["kubernetes.io/change-cause"]`},
+		{`metadata.annotations['kubernetes.io/change-cause']`,
+			`.metadata.annotations["kubernetes.io/change-cause"]`},
+		{`data["*"]`, `.data["*"]`},
+	}
+	for _, test := range tests {
+		p, err := ParsePath(test.path)
+		if err != nil {
+			t.Errorf("ParsePath(%q) failed: %v", test.path, err)
+			continue
+		}
+		if got := p.String(); got != test.want {
+			t.Errorf("ParsePath(%q) = %s, want %s", test.path, got, test.want)
+		}
+
+		// Paths render in the syntax they are parsed from.
+		if again, err := ParsePath(p.String()); err != nil || again.String() != p.String() {
+			t.Errorf("ParsePath(%q) = %v, %v, want %s", p.String(), again, err, p.String())
+		}
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	tests := []string{
+		"spec.containers[0",
+		"spec..containers",
+		"spec.",
+		"spec.containers[-1]",
+		"spec.containers[nginx]",
+		"spec.containers[=nginx]",
+		"spec.ports[port=80,TCP]",
+	}
+	for _, path := range tests {
+		if p, err := ParsePath(path); err == nil {
+			t.Errorf("ParsePath(%q) = %s, want an error", path, p)
+		}
+	}
+}
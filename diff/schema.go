@@ -0,0 +1,220 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	openapi_v2 "github.com/google/gnostic-models/openapiv2"
+	"k8s.io/kube-openapi/pkg/util/proto"
+)
+
+// Schema tells the diff how to match up the elements of an object's lists.
+type Schema interface {
+	// ListKeys returns the fields that identify the elements of the list at `p` (e.g., `name` for a
+	// Pod's containers, or `containerPort` and `protocol` for a container's ports), or nothing if
+	// its elements can only be matched by index. Indices in `p` are ignored.
+	ListKeys(p Path) []string
+}
+
+// openAPISchema is the Schema of one kind, as published by the API server.
+type openAPISchema struct {
+	model proto.Schema
+}
+
+// NewOpenAPISchema finds the schema of `apiVersion` `kind` in the API server's OpenAPI document,
+// e.g., as returned by `watch.OpenAPISchema`. List keys come from the document's
+// `x-kubernetes-patch-merge-key` and `x-kubernetes-list-map-keys` extensions, so lists of CRDs that
+// don't declare them are matched by index.
+func NewOpenAPISchema(doc *openapi_v2.Document, apiVersion, kind string) (Schema, error) {
+	models, err := proto.NewOpenAPIData(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	group, version := "", apiVersion
+	if i := strings.Index(apiVersion, "/"); i != -1 {
+		group, version = apiVersion[:i], apiVersion[i+1:]
+	}
+	for _, name := range models.ListModels() {
+		model := models.LookupModel(name)
+		if model != nil && hasGVK(model, group, version, kind) {
+			return &openAPISchema{model: model}, nil
+		}
+	}
+	return nil, fmt.Errorf("No OpenAPI schema for %s %s", apiVersion, kind)
+}
+
+// hasGVK reports whether `model` is the top-level schema of `group`/`version` `kind`.
+func hasGVK(model proto.Schema, group, version, kind string) bool {
+	gvks, _ := model.GetExtensions()["x-kubernetes-group-version-kind"].([]interface{})
+	for _, gvkI := range gvks {
+		gvk, isMap := gvkI.(map[interface{}]interface{})
+		if !isMap {
+			continue
+		}
+		gvkKind, _ := gvk["kind"].(string)
+		if gvk["group"] == group && gvk["version"] == version && strings.EqualFold(gvkKind, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *openAPISchema) ListKeys(p Path) []string {
+	array, isArray := resolve(lookup(s.model, p)).(*proto.Array)
+	if !isArray {
+		return nil
+	}
+
+	extensions := array.GetExtensions()
+	if keys, isList := extensions["x-kubernetes-list-map-keys"].([]interface{}); isList {
+		names := []string{}
+		for _, key := range keys {
+			if name, isString := key.(string); isString {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	if key, isString := extensions["x-kubernetes-patch-merge-key"].(string); isString {
+		// Strategic merge only needs `containerPort` to tell ports apart, but two ports may share it
+		// if their protocols differ.
+		if key == "containerPort" {
+			if _, hasProtocol := fieldsOf(array.SubType)["protocol"]; hasProtocol {
+				return []string{"containerPort", "protocol"}
+			}
+		}
+		return []string{key}
+	}
+	return nil
+}
+
+// StaticSchema matches up the elements of the lists that many kinds share by the merge keys
+// Kubernetes gives them, without asking the API server: containers, volumes, and environment
+// variables by `name`, volume mounts by `mountPath`, conditions by `type`, and ports by
+// `containerPort` (or, in a Service, `port`) and `protocol`. It stands in for the OpenAPI schema
+// when there is none, e.g., when diffing a recording, or when the API server doesn't publish one.
+var StaticSchema Schema = staticSchema{}
+
+type staticSchema struct{}
+
+// staticListKeys are the keys of the elements of well-known lists, by the name of the field that
+// holds the list.
+var staticListKeys = map[string][]string{
+	"containers":                 {"name"},
+	"initContainers":             {"name"},
+	"ephemeralContainers":        {"name"},
+	"containerStatuses":          {"name"},
+	"initContainerStatuses":      {"name"},
+	"ephemeralContainerStatuses": {"name"},
+	"env":                        {"name"},
+	"volumes":                    {"name"},
+	"volumeMounts":               {"mountPath"},
+	"volumeDevices":              {"devicePath"},
+	"imagePullSecrets":           {"name"},
+	"hostAliases":                {"ip"},
+	"conditions":                 {"type"},
+}
+
+// containerLists are the fields that hold a Pod's containers.
+var containerLists = map[string]bool{
+	"containers": true, "initContainers": true, "ephemeralContainers": true,
+}
+
+func (staticSchema) ListKeys(p Path) []string {
+	if len(p) == 0 || p[len(p)-1].isIndex || p[len(p)-1].wildcard {
+		return nil
+	}
+	field := p[len(p)-1].key
+	if field == "ports" {
+		// A container's ports (`...containers[name=nginx].ports`) are told apart by `containerPort`,
+		// and a Service's by `port`.
+		if len(p) >= 3 && p[len(p)-2].isIndex && containerLists[p[len(p)-3].key] {
+			return []string{"containerPort", "protocol"}
+		}
+		return []string{"port", "protocol"}
+	}
+	return staticListKeys[field]
+}
+
+// lookup walks `p` down from `s`, returning nil if the schema has nothing at `p`.
+func lookup(s proto.Schema, p Path) proto.Schema {
+	for _, seg := range p {
+		switch s := resolve(s).(type) {
+		case *proto.Kind:
+			if seg.isIndex || seg.wildcard {
+				return nil
+			}
+			field, exists := s.Fields[seg.key]
+			if !exists {
+				return nil
+			}
+			return lookup(field, p[1:])
+		case *proto.Map:
+			if seg.isIndex {
+				return nil
+			}
+			return lookup(s.SubType, p[1:])
+		case *proto.Array:
+			if !seg.isIndex && !seg.wildcard {
+				return nil
+			}
+			return lookup(s.SubType, p[1:])
+		default:
+			return nil
+		}
+	}
+	return s
+}
+
+// resolve follows references to the schemas they refer to.
+func resolve(s proto.Schema) proto.Schema {
+	for {
+		ref, isRef := s.(proto.Reference)
+		if !isRef {
+			return s
+		}
+		s = ref.SubSchema()
+	}
+}
+
+func fieldsOf(s proto.Schema) map[string]proto.Schema {
+	if kind, isKind := resolve(s).(*proto.Kind); isKind {
+		return kind.Fields
+	}
+	return nil
+}
+
+// keyOf renders the values of the fields `keys` of the list element `elem`, e.g., `name=nginx`, so
+// that it can be matched with the element of the same key in another version of the list. `ok` is
+// false if `elem` is not an object, or lacks any of the keys.
+func keyOf(elem interface{}, keys []string) (key string, ok bool) {
+	obj, isMap := elem.(map[string]interface{})
+	if !isMap {
+		return "", false
+	}
+	parts := []string{}
+	for _, k := range keys {
+		value, exists := obj[k]
+		if !exists {
+			return "", false
+		}
+		parts = append(parts, fmt.Sprintf("%s=%v", k, value))
+	}
+	return strings.Join(parts, ","), true
+}
+
+// keysOf returns the keys of every element of `list`, or false if they don't all have distinct keys.
+func keysOf(list []interface{}, keys []string) ([]string, bool) {
+	seen := map[string]bool{}
+	result := make([]string, 0, len(list))
+	for _, elem := range list {
+		key, ok := keyOf(elem, keys)
+		if !ok || seen[key] {
+			return nil, false
+		}
+		seen[key] = true
+		result = append(result, key)
+	}
+	return result, true
+}
@@ -0,0 +1,40 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStaticSchema(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"spec.template.spec.containers", []string{"name"}},
+		{"spec.containers[name=nginx].env", []string{"name"}},
+		{"spec.containers[0].volumeMounts", []string{"mountPath"}},
+		{"status.conditions", []string{"type"}},
+		{"spec.containers[0].ports", []string{"containerPort", "protocol"}},
+		{"spec.ports", []string{"port", "protocol"}},
+		{"spec.containers[0].args", nil},
+		{"spec.containers[0]", nil},
+		{"spec.containers[*]", nil},
+		{"", nil},
+	}
+	for _, test := range tests {
+		p, err := ParsePath(test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Keyed paths carry their indices as well as their keys.
+		for i, seg := range p {
+			if seg.match != "" {
+				p[i].index, p[i].isIndex = 0, true
+			}
+		}
+
+		if got := StaticSchema.ListKeys(p); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ListKeys(%s) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// defaultContext is the number of unchanged lines shown around each change, as in `diff -u`.
+const defaultContext = 3
+
+// edit is a single line of an edit script: unchanged (' '), removed ('-'), or added ('+').
+type edit struct {
+	op   byte
+	line string
+}
+
+// Unified renders a unified diff of the YAML of `before` and `after`, with `context` unchanged lines
+// around each change, colored with `colors`.
+func Unified(
+	before, after map[string]interface{}, context int, colors Colors,
+) (text string, modified bool, err error) {
+	beforeYAML, err := yaml.Marshal(before)
+	if err != nil {
+		return "", false, err
+	}
+	afterYAML, err := yaml.Marshal(after)
+	if err != nil {
+		return "", false, err
+	}
+
+	edits := lineEdits(splitLines(string(beforeYAML)), splitLines(string(afterYAML)))
+	hunks := hunksOf(edits, context)
+	if len(hunks) == 0 {
+		return "", false, nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintln(&b, style(colors.File, "--- before"))
+	fmt.Fprintln(&b, style(colors.File, "+++ after"))
+	for _, h := range hunks {
+		fmt.Fprintln(&b, style(colors.Location, h.header()))
+		for _, e := range h.edits {
+			line := string(e.op) + e.line
+			switch e.op {
+			case '-':
+				line = style(colors.Removed, line)
+			case '+':
+				line = style(colors.Added, line)
+			}
+			fmt.Fprintln(&b, line)
+		}
+	}
+	return b.String(), true, nil
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, "\n")
+}
+
+// maxLineEditWork bounds the work of finding the longest common subsequence of the changed lines of
+// two objects, which is proportional to the product of their numbers. Beyond it (e.g., for two
+// versions of a 5,000-line ConfigMap that differ throughout), the changed lines are shown as
+// replaced in a single block.
+const maxLineEditWork = 1 << 24
+
+// lineEdits computes a shortest edit script from `a` to `b`, using the longest common subsequence
+// of their lines. The common prefix and suffix are trimmed first, since most changes to an object
+// touch only a few lines.
+func lineEdits(a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := []edit{}
+	for _, line := range a[:prefix] {
+		edits = append(edits, edit{' ', line})
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if int64(len(midA))*int64(len(midB)) > maxLineEditWork {
+		edits = replaceEdits(edits, midA, midB)
+	} else {
+		edits = lcsEdits(edits, midA, midB)
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, edit{' ', line})
+	}
+	return edits
+}
+
+// lcsEdits appends a shortest edit script from `a` to `b` to `edits`. It uses Hirschberg's
+// algorithm, which needs space only linear in the number of lines: it finds where the longest
+// common subsequence crosses the middle of `a`, and solves each half on its own.
+func lcsEdits(edits []edit, a, b []string) []edit {
+	switch {
+	case len(a) == 0 || len(b) == 0:
+		return replaceEdits(edits, a, b)
+	case len(a) == 1:
+		for j, line := range b {
+			if line == a[0] {
+				edits = replaceEdits(edits, nil, b[:j])
+				edits = append(edits, edit{' ', line})
+				return replaceEdits(edits, nil, b[j+1:])
+			}
+		}
+		return replaceEdits(edits, a, b)
+	}
+
+	mid := len(a) / 2
+	// forward[j] is the length of the longest common subsequence of a[:mid] and b[:j], and
+	// backward[j] that of a[mid:] and b[j:].
+	forward := lcsLengths(a[:mid], b)
+	backward := lcsLengths(reversed(a[mid:]), reversed(b))
+	split, longest := 0, int32(-1)
+	for j := 0; j <= len(b); j++ {
+		if length := forward[j] + backward[len(b)-j]; length > longest {
+			split, longest = j, length
+		}
+	}
+
+	edits = lcsEdits(edits, a[:mid], b[:split])
+	return lcsEdits(edits, a[mid:], b[split:])
+}
+
+// lcsLengths is the last row of the table of the lengths of the longest common subsequences of `a`
+// and each prefix of `b`, i.e., the length of that of `a` and `b[:j]` for each `j`.
+func lcsLengths(a, b []string) []int32 {
+	prev, cur := make([]int32, len(b)+1), make([]int32, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] >= cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev
+}
+
+// replaceEdits appends the removal of every line of `a`, and then the addition of every line of
+// `b`, to `edits`.
+func replaceEdits(edits []edit, a, b []string) []edit {
+	for _, line := range a {
+		edits = append(edits, edit{'-', line})
+	}
+	for _, line := range b {
+		edits = append(edits, edit{'+', line})
+	}
+	return edits
+}
+
+func reversed(lines []string) []string {
+	r := make([]string, len(lines))
+	for i, line := range lines {
+		r[len(lines)-1-i] = line
+	}
+	return r
+}
+
+// hunk is a run of changes, along with their surrounding context.
+type hunk struct {
+	beforeStart, beforeLines int
+	afterStart, afterLines   int
+	edits                    []edit
+}
+
+func (h hunk) header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.beforeStart, h.beforeLines),
+		hunkRange(h.afterStart, h.afterLines))
+}
+
+func hunkRange(start, lines int) string {
+	if lines == 0 {
+		// By convention, an empty range starts at the line before it.
+		return fmt.Sprintf("%d,0", start-1)
+	} else if lines == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// hunksOf groups `edits` into hunks, each with up to `context` unchanged lines before and after its
+// changes. Hunks whose context would overlap are merged.
+func hunksOf(edits []edit, context int) []hunk {
+	hunks := []hunk{}
+
+	// beforeLine[i] and afterLine[i] are the 1-based line numbers of edits[i] in each file.
+	beforeLine, afterLine := make([]int, len(edits)), make([]int, len(edits))
+	b, a := 1, 1
+	for i, e := range edits {
+		beforeLine[i], afterLine[i] = b, a
+		if e.op != '+' {
+			b++
+		}
+		if e.op != '-' {
+			a++
+		}
+	}
+
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		// Extend the hunk until a run of more than 2*context unchanged lines, or the end.
+		end := i
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].op == ' ' {
+				run++
+			}
+			if run == len(edits) || run-end > 2*context {
+				break
+			}
+			end = run
+		}
+		stop := end + context
+		if stop > len(edits) {
+			stop = len(edits)
+		}
+
+		h := hunk{beforeStart: beforeLine[start], afterStart: afterLine[start], edits: edits[start:stop]}
+		for _, e := range h.edits {
+			if e.op != '+' {
+				h.beforeLines++
+			}
+			if e.op != '-' {
+				h.afterLines++
+			}
+		}
+		hunks = append(hunks, h)
+		i = stop
+	}
+	return hunks
+}
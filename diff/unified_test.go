@@ -0,0 +1,156 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// numbered is the lines "1" through "n".
+func numbered(n int) []string {
+	lines := []string{}
+	for i := 1; i <= n; i++ {
+		lines = append(lines, fmt.Sprint(i))
+	}
+	return lines
+}
+
+// replaced is `lines`, with the line numbered `n` in each of `changed` replaced.
+func replaced(lines []string, changed ...int) []string {
+	r := append([]string{}, lines...)
+	for _, n := range changed {
+		r[n-1] = "changed"
+	}
+	return r
+}
+
+func TestHunks(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    []string
+		context int
+		want    []string
+	}{
+		{"unchanged", numbered(5), numbered(5), 3, []string{}},
+		{"one change", numbered(20), replaced(numbered(20), 10), 3, []string{"@@ -7,7 +7,7 @@"}},
+		{"no context", numbered(20), replaced(numbered(20), 10), 0, []string{"@@ -10 +10 @@"}},
+		{"context cut off at start", numbered(20), replaced(numbered(20), 2), 3,
+			[]string{"@@ -1,5 +1,5 @@"}},
+		{"context cut off at end", numbered(20), replaced(numbered(20), 19), 3,
+			[]string{"@@ -16,5 +16,5 @@"}},
+		// Six unchanged lines between two changes are the context of both, so the hunks are merged.
+		{"contexts touching", numbered(20), replaced(numbered(20), 5, 12), 3,
+			[]string{"@@ -2,14 +2,14 @@"}},
+		// With seven, they are not.
+		{"contexts apart", numbered(20), replaced(numbered(20), 5, 13), 3,
+			[]string{"@@ -2,7 +2,7 @@", "@@ -10,7 +10,7 @@"}},
+		{"added to empty", []string{}, []string{"a"}, 3, []string{"@@ -0,0 +1 @@"}},
+		{"emptied", []string{"a", "b"}, []string{}, 3, []string{"@@ -1,2 +0,0 @@"}},
+		{"line inserted", numbered(3), []string{"1", "2", "new", "3"}, 0, []string{"@@ -2,0 +3 @@"}},
+		{"line removed", numbered(3), []string{"1", "3"}, 1, []string{"@@ -1,3 +1,2 @@"}},
+	}
+	for _, test := range tests {
+		got := []string{}
+		for _, h := range hunksOf(lineEdits(test.a, test.b), test.context) {
+			got = append(got, h.header())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: hunk headers = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		want          string
+	}{
+		{"unchanged", `{"a": 1}`, `{"a": 1}`, ""},
+		{
+			"field changed",
+			`{"a": 1, "b": 2, "c": 3}`,
+			`{"a": 1, "b": 4, "c": 3}`,
+			"--- before\n+++ after\n@@ -1,3 +1,3 @@\n a: 1\n-b: 2\n+b: 4\n c: 3\n",
+		},
+		{
+			"field added",
+			`{"a": 1}`,
+			`{"a": 1, "b": [1, 2]}`,
+			"--- before\n+++ after\n@@ -1 +1,4 @@\n a: 1\n+b:\n+- 1\n+- 2\n",
+		},
+	}
+	for _, test := range tests {
+		got, modified, err := Unified(object(t, test.before), object(t, test.after), defaultContext,
+			Colors{})
+		if err != nil {
+			t.Errorf("%s: Unified failed: %v", test.name, err)
+		} else if got != test.want || modified != (test.want != "") {
+			t.Errorf("%s: Unified = %q, %v, want %q", test.name, got, modified, test.want)
+		}
+	}
+}
+
+func TestLineEdits(t *testing.T) {
+	// Once the changed lines are too many to compare with each other, they are shown as replaced
+	// whole, even when most of them only moved.
+	tooMany := 1
+	for tooMany*tooMany <= maxLineEditWork {
+		tooMany++
+	}
+
+	tests := []struct {
+		name                   string
+		lines                  int
+		removed, added, common int
+	}{
+		{"few lines", 100, 1, 1, 99},
+		{"most lines", tooMany - 1, 1, 1, tooMany - 2},
+		{"too many lines", tooMany, tooMany, tooMany, 0},
+	}
+	for _, test := range tests {
+		// Move the first line to the end, so that no line is common to the start or end of both.
+		a := numbered(test.lines)
+		b := append(append([]string{}, a[1:]...), a[0])
+
+		counts := map[byte]int{}
+		for _, e := range lineEdits(a, b) {
+			counts[e.op]++
+		}
+		if counts['-'] != test.removed || counts['+'] != test.added || counts[' '] != test.common {
+			t.Errorf("%s: lineEdits removed %d, added %d, and kept %d lines, want %d, %d, and %d",
+				test.name, counts['-'], counts['+'], counts[' '], test.removed, test.added,
+				test.common)
+		}
+	}
+}
+
+func TestLineEditsApply(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"a b c d e", "a c d f e"},
+		{"a b c", "c b a"},
+		{"a a b a", "b a a a"},
+		{"", "a b"},
+		{"x y z", "a b c"},
+	}
+	for _, test := range tests {
+		a, b := strings.Fields(test.a), strings.Fields(test.b)
+		edits := lineEdits(a, b)
+
+		// The edits must spell out `a` when read without additions, and `b` without removals.
+		gotA, gotB := []string{}, []string{}
+		for _, e := range edits {
+			if e.op != '+' {
+				gotA = append(gotA, e.line)
+			}
+			if e.op != '-' {
+				gotB = append(gotB, e.line)
+			}
+		}
+		if !reflect.DeepEqual(gotA, a) || !reflect.DeepEqual(gotB, b) {
+			t.Errorf("lineEdits(%q, %q) = %v, which is not an edit script between them", test.a,
+				test.b, edits)
+		}
+	}
+}
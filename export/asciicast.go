@@ -0,0 +1,128 @@
+// Package export renders recorded sessions into formats that can be shared outside of kubespy, e.g.,
+// asciinema casts, so that docs and talks can be made reproducibly from a recording rather than by
+// capturing a screen.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Frame is what the terminal shows from time At on, counting from the start of the cast.
+type Frame struct {
+	At   time.Duration
+	Text string
+}
+
+// CastOptions control how a cast is written.
+type CastOptions struct {
+	// Width and Height are the size of the terminal, in columns and rows. If either is 0, it is
+	// chosen to fit every frame, and to be at least 80 by 24.
+	Width, Height int
+
+	Title     string
+	Timestamp time.Time
+
+	// IdleTimeLimit, if nonzero, is the longest pause players show between frames.
+	IdleTimeLimit time.Duration
+}
+
+// castHeader is the first line of an asciinema v2 cast. See
+// https://docs.asciinema.org/manual/asciicast/v2/.
+type castHeader struct {
+	Version       int               `json:"version"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	Timestamp     int64             `json:"timestamp,omitempty"`
+	IdleTimeLimit float64           `json:"idle_time_limit,omitempty"`
+	Title         string            `json:"title,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`
+}
+
+const (
+	minWidth, minHeight = 80, 24
+
+	// clearScreen moves the cursor to the top left, and clears the screen, so that each frame is
+	// drawn in place of the one before.
+	clearScreen = "\x1b[H\x1b[2J"
+)
+
+var escapeCodes = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]")
+
+// WriteCast writes `frames` to `w` as an asciinema v2 cast, which clears the screen and draws each
+// frame at its time. A frame that is the same as the one before is left out.
+func WriteCast(w io.Writer, frames []Frame, opts CastOptions) error {
+	header := castHeader{
+		Version: 2,
+		Width:   opts.Width,
+		Height:  opts.Height,
+		Title:   opts.Title,
+		Env:     map[string]string{"TERM": "xterm-256color"},
+	}
+	if header.Width == 0 || header.Height == 0 {
+		width, height := fit(frames)
+		if header.Width == 0 {
+			header.Width = width
+		}
+		if header.Height == 0 {
+			header.Height = height
+		}
+	}
+	if !opts.Timestamp.IsZero() {
+		header.Timestamp = opts.Timestamp.Unix()
+	}
+	if opts.IdleTimeLimit > 0 {
+		header.IdleTimeLimit = opts.IdleTimeLimit.Seconds()
+	}
+	if err := writeLine(w, header); err != nil {
+		return err
+	}
+
+	last := ""
+	for i, frame := range frames {
+		if i > 0 && frame.Text == last {
+			continue
+		}
+		last = frame.Text
+
+		// The terminal is not in cooked mode, so every line feed needs a carriage return.
+		text := clearScreen + strings.ReplaceAll(frame.Text, "\n", "\r\n")
+		if err := writeLine(w, []interface{}{frame.At.Seconds(), "o", text}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fit is the size of the smallest terminal that shows every frame in full, but at least 80 by 24.
+func fit(frames []Frame) (width, height int) {
+	width, height = minWidth, minHeight
+	for _, frame := range frames {
+		lines := strings.Split(strings.TrimSuffix(frame.Text, "\n"), "\n")
+		if len(lines)+1 > height {
+			// Leave a line for the cursor.
+			height = len(lines) + 1
+		}
+		for _, line := range lines {
+			if n := runewidth.StringWidth(escapeCodes.ReplaceAllString(line, "")); n > width {
+				width = n
+			}
+		}
+	}
+	return width, height
+}
+
+func writeLine(w io.Writer, v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("Unable to encode cast: %v", err)
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
@@ -17,3 +17,17 @@ func New() clientcmd.ClientConfig {
 	loadingRules.DefaultClientConfig = &clientcmd.DefaultClientConfig
 	return clientcmd.NewInteractiveDeferredLoadingClientConfig(loadingRules, overrides, os.Stdin)
 }
+
+// CurrentContext returns the name of the kubeconfig's current context, and of the cluster it points
+// to.
+func CurrentContext() (context, cluster string, err error) {
+	raw, err := New().RawConfig()
+	if err != nil {
+		return "", "", err
+	}
+	context = raw.CurrentContext
+	if c, exists := raw.Contexts[context]; exists {
+		cluster = c.Cluster
+	}
+	return context, cluster, nil
+}
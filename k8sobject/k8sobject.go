@@ -1,6 +1,8 @@
 package k8sobject
 
 import (
+	"fmt"
+
 	"github.com/pulumi/pulumi-kubernetes/provider/v4/pkg/openapi"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
@@ -58,3 +60,36 @@ func PodContainerStatuses(pod *unstructured.Unstructured) []interface{} {
 
 	return containerStatuses
 }
+
+// Readiness reports whether an object is ready, and why. It uses the `Ready` condition if the object
+// has one, then the `Available` condition, and finally falls back to comparing replica counts. If
+// none of these are present, `status` is empty.
+func Readiness(o *unstructured.Unstructured) (status, reason string) {
+	conditionsI, _ := openapi.Pluck(o.Object, "status", "conditions")
+	conditions, _ := conditionsI.([]interface{})
+	for _, conditionType := range []string{"Ready", "Available"} {
+		for _, conditionI := range conditions {
+			condition, isMap := conditionI.(map[string]interface{})
+			if !isMap || condition["type"] != conditionType {
+				continue
+			}
+
+			status, _ = condition["status"].(string)
+			reason, _ = condition["reason"].(string)
+			return status, reason
+		}
+	}
+
+	replicasI, hasReplicas := openapi.Pluck(o.Object, "status", "replicas")
+	replicas, isInt := replicasI.(int64)
+	if !hasReplicas || !isInt {
+		return "", ""
+	}
+
+	readyReplicasI, _ := openapi.Pluck(o.Object, "status", "readyReplicas")
+	readyReplicas, _ := readyReplicasI.(int64)
+	if readyReplicas < replicas {
+		return "False", fmt.Sprintf("%d/%d replicas ready", readyReplicas, replicas)
+	}
+	return "True", ""
+}
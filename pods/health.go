@@ -0,0 +1,319 @@
+package pods
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pulumi/kubespy/k8sobject"
+	"github.com/pulumi/pulumi-kubernetes/provider/v4/pkg/openapi"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ContainerType distinguishes the different kinds of containers a Pod can run.
+type ContainerType string
+
+const (
+	// Init containers run to completion, in order, before any other container starts.
+	Init ContainerType = "init"
+	// Sidecar containers are init containers with `restartPolicy: Always`. They start in order
+	// with the other init containers, but keep running alongside the app containers.
+	Sidecar ContainerType = "sidecar"
+	// App containers are the regular containers in `.spec.containers`.
+	App ContainerType = "app"
+	// Ephemeral containers are added to a running Pod, usually by `kubectl debug`.
+	Ephemeral ContainerType = "ephemeral"
+)
+
+const (
+	crashLoopBackOff = "CrashLoopBackOff"
+
+	// unhealthy is the reason of the Events the kubelet reports when a probe fails, and of the
+	// Problem of a container that is failing its probes.
+	unhealthy = "Unhealthy"
+
+	// Image pull errors have a bunch of useless junk at the beginning of the error message.
+	imagePullJunk = "rpc error: code = Unknown desc = Error response from daemon: "
+)
+
+// The kubelet reports the current CrashLoopBackOff delay in the waiting message, e.g.,
+// `back-off 2m40s restarting failed container=app pod=app-5d4f8_default(...)`.
+var backOffRegex = regexp.MustCompile(`back-off (\S+) restarting`)
+
+// Events about one of a Pod's containers name it in their `fieldPath`, e.g., `spec.containers{app}`.
+var containerFieldPathRegex = regexp.MustCompile(`^spec\.\w+\{(.+)\}$`)
+
+// Problem is a reason and a human-readable message explaining why some part of a Pod is unhealthy.
+type Problem struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+}
+
+// Termination describes the last time a container exited.
+type Termination struct {
+	Reason     string    `json:"reason,omitempty"`
+	Message    string    `json:"message,omitempty"`
+	ExitCode   int64     `json:"exitCode"`
+	FinishedAt time.Time `json:"finishedAt,omitempty"`
+}
+
+// ContainerHealth summarizes the health of a single container in a Pod.
+type ContainerHealth struct {
+	Name string        `json:"name"`
+	Type ContainerType `json:"type"`
+
+	// State is one of `Waiting`, `Running`, or `Terminated`.
+	State   string `json:"state"`
+	Ready   bool   `json:"ready"`
+	Started bool   `json:"started"`
+
+	// Problem explains why the container is waiting or terminated, or why its probes are failing.
+	// Nil if the container is healthy.
+	Problem *Problem `json:"problem,omitempty"`
+
+	// NotReady explains why a running container is not ready yet, e.g., `readiness probe has not yet
+	// succeeded`. Every new container is briefly not ready while it starts, so this is not a problem
+	// until there is evidence that its probes are failing: a restart, or an `Unhealthy` Event.
+	NotReady string `json:"notReady,omitempty"`
+
+	RestartCount    int64        `json:"restartCount"`
+	LastTermination *Termination `json:"lastTermination,omitempty"`
+
+	// BackOff is how long the kubelet is waiting between restarts of a container in
+	// CrashLoopBackOff, and NextRestart is roughly when it will try again.
+	BackOff     time.Duration `json:"backOff,omitempty"`
+	NextRestart time.Time     `json:"nextRestart,omitempty"`
+}
+
+// Failing is true if the container is waiting on something other than its own startup, has
+// exited unsuccessfully, or is evidently failing its probes.
+func (c ContainerHealth) Failing() bool {
+	return c.Problem != nil
+}
+
+// Health summarizes the health of a Pod and each of its containers.
+type Health struct {
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace"`
+	UID       types.UID `json:"uid,omitempty"`
+	Phase     string    `json:"phase,omitempty"`
+	Ready     bool      `json:"ready"`
+
+	// Conditions holds the reasons any of the `PodScheduled`, `Initialized`, or `Ready` conditions
+	// are not yet true.
+	Conditions []Problem `json:"conditions,omitempty"`
+
+	// InitCompleted out of InitTotal init containers have run to completion. Sidecars count as
+	// complete once they have started.
+	InitCompleted int `json:"initCompleted"`
+	InitTotal     int `json:"initTotal"`
+
+	Containers []ContainerHealth `json:"containers"`
+}
+
+// Failing is true if any of the Pod's containers are failing.
+func (h Health) Failing() bool {
+	for _, c := range h.Containers {
+		if c.Failing() {
+			return true
+		}
+	}
+	return false
+}
+
+// Restarts is the total number of restarts across all of the Pod's containers.
+func (h Health) Restarts() int64 {
+	var restarts int64
+	for _, c := range h.Containers {
+		restarts += c.RestartCount
+	}
+	return restarts
+}
+
+// ObserveEvent takes the Kubernetes Event regarding the Pod, with reason `reason`, about the part
+// of the Pod at `fieldPath`, into account. An `Unhealthy` Event, which the kubelet reports when one
+// of a container's probes fails, is the evidence that a container that is not ready yet is failing
+// its probes, rather than just starting. If `fieldPath` names no container, every container that is
+// not ready yet is taken to be failing.
+func (h *Health) ObserveEvent(reason, fieldPath, message string) {
+	if reason != unhealthy {
+		return
+	}
+
+	container := ""
+	if match := containerFieldPathRegex.FindStringSubmatch(fieldPath); match != nil {
+		container = match[1]
+	}
+	for i := range h.Containers {
+		c := &h.Containers[i]
+		if c.NotReady == "" || (container != "" && c.Name != container) {
+			continue
+		}
+		c.NotReady = ""
+		c.Problem = &Problem{Reason: unhealthy, Message: strings.TrimSpace(message)}
+	}
+}
+
+// GetHealth computes the health of `pod` from its spec and status.
+func GetHealth(pod *unstructured.Unstructured) Health {
+	health := Health{
+		Name: pod.GetName(), Namespace: pod.GetNamespace(), UID: pod.GetUID(),
+		Containers: []ContainerHealth{},
+	}
+	phaseI, _ := openapi.Pluck(pod.Object, "status", "phase")
+	health.Phase, _ = phaseI.(string)
+
+	for _, conditionI := range k8sobject.PodConditions(pod) {
+		condition, isMap := conditionI.(map[string]interface{})
+		if !isMap {
+			continue
+		}
+
+		conditionType := condition["type"]
+		if conditionType == "Ready" {
+			health.Ready = condition["status"] == "True"
+		}
+		if conditionType != "PodScheduled" && conditionType != "Initialized" && conditionType != "Ready" ||
+			condition["status"] == "True" {
+			continue
+		}
+
+		reason, _ := condition["reason"].(string)
+		message, _ := condition["message"].(string)
+		if reason != "" && message != "" {
+			health.Conditions = append(health.Conditions, Problem{Reason: reason, Message: message})
+		}
+	}
+
+	// The spec tells us which init containers are sidecars, and which containers have probes.
+	specs := map[string]map[string]interface{}{}
+	for _, field := range []string{"initContainers", "containers", "ephemeralContainers"} {
+		containersI, _ := openapi.Pluck(pod.Object, "spec", field)
+		containers, _ := containersI.([]interface{})
+		for _, containerI := range containers {
+			if container, isMap := containerI.(map[string]interface{}); isMap {
+				name, _ := container["name"].(string)
+				specs[name] = container
+			}
+		}
+	}
+
+	statusFields := []struct {
+		field         string
+		containerType ContainerType
+	}{
+		{"initContainerStatuses", Init},
+		{"containerStatuses", App},
+		{"ephemeralContainerStatuses", Ephemeral},
+	}
+	for _, sf := range statusFields {
+		statusesI, _ := openapi.Pluck(pod.Object, "status", sf.field)
+		statuses, _ := statusesI.([]interface{})
+		for _, statusI := range statuses {
+			status, isMap := statusI.(map[string]interface{})
+			if !isMap {
+				continue
+			}
+
+			c := containerHealth(status, sf.containerType, specs)
+			if c.Type == Init || c.Type == Sidecar {
+				health.InitTotal++
+				if (c.Type == Init && c.State == "Terminated" && c.Problem == nil) ||
+					(c.Type == Sidecar && c.Started) {
+					health.InitCompleted++
+				}
+			}
+			health.Containers = append(health.Containers, c)
+		}
+	}
+
+	return health
+}
+
+func containerHealth(
+	status map[string]interface{}, containerType ContainerType, specs map[string]map[string]interface{},
+) ContainerHealth {
+	c := ContainerHealth{Type: containerType}
+	c.Name, _ = status["name"].(string)
+	c.Ready, _ = status["ready"].(bool)
+	c.Started, _ = status["started"].(bool)
+	c.RestartCount, _ = status["restartCount"].(int64)
+
+	spec := specs[c.Name]
+	if c.Type == Init && spec["restartPolicy"] == "Always" {
+		c.Type = Sidecar
+	}
+
+	if terminatedI, _ := openapi.Pluck(status, "lastState", "terminated"); terminatedI != nil {
+		if terminated, isMap := terminatedI.(map[string]interface{}); isMap {
+			c.LastTermination = termination(terminated)
+		}
+	}
+
+	waitingI, _ := openapi.Pluck(status, "state", "waiting")
+	terminatedI, _ := openapi.Pluck(status, "state", "terminated")
+	if waiting, isMap := waitingI.(map[string]interface{}); isMap {
+		c.State = "Waiting"
+		c.Problem = checkWaitingContainer(waiting)
+		if c.Problem != nil && c.Problem.Reason == crashLoopBackOff {
+			if match := backOffRegex.FindStringSubmatch(c.Problem.Message); match != nil {
+				c.BackOff, _ = time.ParseDuration(match[1])
+			}
+			if c.BackOff > 0 && c.LastTermination != nil && !c.LastTermination.FinishedAt.IsZero() {
+				c.NextRestart = c.LastTermination.FinishedAt.Add(c.BackOff)
+			}
+		}
+	} else if terminated, isMap := terminatedI.(map[string]interface{}); isMap {
+		c.State = "Terminated"
+		t := termination(terminated)
+		if t.ExitCode != 0 {
+			message := t.Message
+			if message == "" {
+				message = fmt.Sprintf("Container completed with exit code %d", t.ExitCode)
+			}
+			c.Problem = &Problem{Reason: t.Reason, Message: message}
+		}
+	} else {
+		c.State = "Running"
+		probe := ""
+		if _, hasStartupProbe := spec["startupProbe"]; hasStartupProbe && !c.Started {
+			probe = "startup"
+		} else if _, hasReadinessProbe := spec["readinessProbe"]; hasReadinessProbe && !c.Ready {
+			probe = "readiness"
+		}
+		if probe != "" && c.RestartCount > 0 {
+			// A container that has already restarted is failing, not starting.
+			c.Problem = &Problem{Reason: unhealthy, Message: probe + " probe failing"}
+		} else if probe != "" {
+			c.NotReady = probe + " probe has not yet succeeded"
+		}
+	}
+
+	return c
+}
+
+func checkWaitingContainer(waiting map[string]interface{}) *Problem {
+	reason, _ := waiting["reason"].(string)
+	if reason == "" || reason == "ContainerCreating" || reason == "PodInitializing" {
+		return nil
+	}
+
+	message, _ := waiting["message"].(string)
+	return &Problem{Reason: reason, Message: strings.TrimPrefix(message, imagePullJunk)}
+}
+
+func termination(terminated map[string]interface{}) *Termination {
+	t := &Termination{}
+	t.Reason, _ = terminated["reason"].(string)
+	t.Message, _ = terminated["message"].(string)
+	t.ExitCode, _ = terminated["exitCode"].(int64)
+	if finishedAt, isString := terminated["finishedAt"].(string); isString {
+		t.FinishedAt, _ = time.Parse(time.RFC3339, finishedAt)
+	}
+	if t.Reason == "" && t.ExitCode != 0 {
+		t.Reason = "Error"
+	}
+	return t
+}
@@ -0,0 +1,96 @@
+package pods
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// runningPod is a Pod whose only container, `app`, is running, with a readiness probe.
+func runningPod(ready bool, restarts int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "nginx", "namespace": "default", "uid": "u1"},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{
+					"name":           "app",
+					"readinessProbe": map[string]interface{}{},
+				},
+			},
+		},
+		"status": map[string]interface{}{
+			"containerStatuses": []interface{}{
+				map[string]interface{}{
+					"name":         "app",
+					"ready":        ready,
+					"started":      true,
+					"restartCount": restarts,
+					"state":        map[string]interface{}{"running": map[string]interface{}{}},
+				},
+			},
+		},
+	}}
+}
+
+func TestReadiness(t *testing.T) {
+	type event struct{ reason, fieldPath, message string }
+	tests := []struct {
+		name         string
+		ready        bool
+		restarts     int64
+		events       []event
+		wantProblem  string
+		wantNotReady bool
+	}{
+		{name: "ready", ready: true},
+		// Every new container is briefly not ready while it starts.
+		{name: "starting", wantNotReady: true},
+		{
+			name:         "starting, with unrelated events",
+			events:       []event{{"Pulled", "spec.containers{app}", "Successfully pulled image"}},
+			wantNotReady: true,
+		},
+		{
+			name:         "probe failing in another container",
+			events:       []event{{"Unhealthy", "spec.containers{sidecar}", "Readiness probe failed"}},
+			wantNotReady: true,
+		},
+		{
+			name:        "probe failing",
+			events:      []event{{"Unhealthy", "spec.containers{app}", "Readiness probe failed: 500"}},
+			wantProblem: "Readiness probe failed: 500",
+		},
+		{
+			name:        "probe failing, in some container",
+			events:      []event{{"Unhealthy", "", "Readiness probe failed: 500"}},
+			wantProblem: "Readiness probe failed: 500",
+		},
+		{name: "restarted", restarts: 1, wantProblem: "readiness probe failing"},
+		{
+			name:   "ready again after failing",
+			ready:  true,
+			events: []event{{"Unhealthy", "spec.containers{app}", "Readiness probe failed: 500"}},
+		},
+	}
+	for _, test := range tests {
+		health := GetHealth(runningPod(test.ready, test.restarts))
+		for _, e := range test.events {
+			health.ObserveEvent(e.reason, e.fieldPath, e.message)
+		}
+
+		c := health.Containers[0]
+		problem := ""
+		if c.Problem != nil {
+			problem = c.Problem.Message
+		}
+		if problem != test.wantProblem || (c.NotReady != "") != test.wantNotReady {
+			t.Errorf("%s: expected problem %q and not ready %t, got %+v",
+				test.name, test.wantProblem, test.wantNotReady, c)
+		}
+		if c.Failing() != (test.wantProblem != "") {
+			t.Errorf("%s: expected failing to be %t", test.name, test.wantProblem != "")
+		}
+	}
+}
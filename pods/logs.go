@@ -0,0 +1,278 @@
+package pods
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pulumi/kubespy/k8sconfig"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	logFetchTimeout = 5 * time.Second
+
+	// maxLogFetches is how many logs a LogTailer fetches at once.
+	maxLogFetches = 4
+
+	// logRefreshInterval is how often the tail of a container's current log is fetched again, since
+	// the container is still writing to it.
+	logRefreshInterval = 10 * time.Second
+
+	// A log that could not be fetched (e.g., because the user may not read logs) is tried again
+	// after minLogRetry, and then after twice as long each time, up to maxLogRetry.
+	minLogRetry = 5 * time.Second
+	maxLogRetry = 5 * time.Minute
+)
+
+// maxCachedTails is how many containers' log tails a LogTailer keeps. Beyond that, the tails of the
+// containers whose logs were asked for least recently (e.g., those of deleted Pods) are evicted.
+const maxCachedTails = 256
+
+// LogSource fetches the last `lines` lines of a container's log. If `previous` is true, it fetches
+// the log of the container's previous instance, i.e., the one that most recently exited.
+type LogSource interface {
+	Tail(namespace, pod, container string, previous bool, lines int64) ([]string, error)
+}
+
+// NewLogSource creates a LogSource that reads logs from the API server, as `kubectl logs` would.
+func NewLogSource() (LogSource, error) {
+	conf, err := k8sconfig.New().ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read kubectl config: %v", err)
+	}
+
+	clientSet, err := kubernetes.NewForConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiServerLogSource{clientSet: clientSet}, nil
+}
+
+type apiServerLogSource struct {
+	clientSet kubernetes.Interface
+}
+
+func (s *apiServerLogSource) Tail(
+	namespace, pod, container string, previous bool, lines int64,
+) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), logFetchTimeout)
+	defer cancel()
+
+	raw, err := s.clientSet.CoreV1().Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container: container,
+		Previous:  previous,
+		TailLines: &lines,
+	}).DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.TrimRight(string(raw), "\n")
+	if text == "" {
+		return []string{}, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+// LogTail is the tail of a container's log.
+type LogTail struct {
+	// Previous is true if these lines were logged by the container's previous instance.
+	Previous bool     `json:"previous"`
+	Lines    []string `json:"lines"`
+	Err      error    `json:"-"`
+
+	// Pending is true until the log has been fetched for the first time.
+	Pending bool `json:"pending,omitempty"`
+}
+
+// MarshalJSON reports any error fetching the log as a string.
+func (t LogTail) MarshalJSON() ([]byte, error) {
+	type plainLogTail LogTail
+	var errString string
+	if t.Err != nil {
+		errString = t.Err.Error()
+	}
+	return json.Marshal(struct {
+		plainLogTail
+		Error string `json:"error,omitempty"`
+	}{plainLogTail(t), errString})
+}
+
+// LogTailer fetches the tails of the logs of failing containers from a LogSource. Logs are fetched in
+// the background, so that asking for one never waits on the API server: until a log has been
+// fetched, its tail is pending, and each time a fetch finishes, LogTailer signals on Updates.
+//
+// Logs are cached per container instance. The log of a container's previous instance never
+// changes, so it is fetched once; the current one is fetched again every so often while it is asked
+// for. Logs that could not be fetched are tried again with an exponential backoff. Pods are told
+// apart by UID, so a Pod that is replaced by another of the same name (e.g., by a StatefulSet) has
+// its logs fetched afresh.
+type LogTailer struct {
+	source LogSource
+	lines  int64
+
+	// updates is signaled whenever a fetch finishes, or a tail is due to be fetched again.
+	updates chan struct{}
+	// fetching limits how many logs are fetched at once.
+	fetching chan struct{}
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[tailKey]*cachedTail
+	uses  uint64
+}
+
+type tailKey struct {
+	pod       types.UID
+	container string
+}
+
+// cachedTail is the tail of the log of the instance of a container that restarted `restartCount`
+// times, or of the one before it, if `previous` is true.
+type cachedTail struct {
+	restartCount int64
+	previous     bool
+	tail         LogTail
+
+	// fetched is when the tail was last fetched (or failed to be), and failures is how many times in
+	// a row fetching it has failed. inFlight is true while it is being fetched.
+	fetched  time.Time
+	failures int
+	inFlight bool
+
+	// used is when the tail was last asked for, counted in calls to LogTailer.Tail.
+	used uint64
+}
+
+// due is true if the tail should be fetched (again) at `now`.
+func (c *cachedTail) due(now time.Time) bool {
+	switch {
+	case c.inFlight:
+		return false
+	case c.fetched.IsZero():
+		return true
+	case c.failures > 0:
+		return !now.Before(c.fetched.Add(retryAfter(c.failures)))
+	default:
+		return !c.previous && !now.Before(c.fetched.Add(logRefreshInterval))
+	}
+}
+
+// retryAfter is how long to wait before fetching a log again, after `failures` failures in a row.
+func retryAfter(failures int) time.Duration {
+	wait := minLogRetry
+	for i := 1; i < failures && wait < maxLogRetry; i++ {
+		wait *= 2
+	}
+	if wait > maxLogRetry {
+		return maxLogRetry
+	}
+	return wait
+}
+
+// NewLogTailer creates a LogTailer that fetches the last `lines` lines of logs from `source`.
+func NewLogTailer(source LogSource, lines int64) *LogTailer {
+	return &LogTailer{
+		source:   source,
+		lines:    lines,
+		updates:  make(chan struct{}, 1),
+		fetching: make(chan struct{}, maxLogFetches),
+		now:      time.Now,
+		cache:    map[tailKey]*cachedTail{},
+	}
+}
+
+// Updates is signaled whenever the tail of a log that was asked for may have changed, i.e., when it
+// has been fetched, or is due to be fetched again. Tails should then be asked for again.
+func (t *LogTailer) Updates() <-chan struct{} {
+	return t.updates
+}
+
+// Tail returns the tail of the log of the container `c` in the Pod described by `health`, as last
+// fetched, and fetches it in the background if it has not been yet, or is due to be again. If the
+// container is not currently running and has restarted, the previous instance's log is returned,
+// since that is the one that explains the failure.
+func (t *LogTailer) Tail(health Health, c ContainerHealth) LogTail {
+	previous := c.State != "Running" && c.RestartCount > 0
+	key := tailKey{pod: health.UID, container: c.Name}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.uses++
+	cached, exists := t.cache[key]
+	if !exists || cached.restartCount != c.RestartCount || cached.previous != previous {
+		cached = &cachedTail{
+			restartCount: c.RestartCount, previous: previous,
+			tail: LogTail{Previous: previous, Lines: []string{}, Pending: true},
+		}
+		t.cache[key] = cached
+	}
+	cached.used = t.uses
+	if len(t.cache) > maxCachedTails {
+		t.evictLeastRecentlyUsed()
+	}
+
+	if cached.due(t.now()) {
+		cached.inFlight = true
+		go t.fetch(key, cached, health.Namespace, health.Name, c.Name)
+	}
+	return cached.tail
+}
+
+// fetch fetches the tail `cached` of the log of `container` in the Pod `namespace/name`.
+func (t *LogTailer) fetch(key tailKey, cached *cachedTail, namespace, name, container string) {
+	t.fetching <- struct{}{}
+	lines, err := t.source.Tail(namespace, name, container, cached.previous, t.lines)
+	<-t.fetching
+
+	t.mu.Lock()
+	cached.inFlight, cached.fetched = false, t.now()
+	if err != nil {
+		cached.failures++
+		// Keep showing the last lines fetched, if any, along with the error.
+		cached.tail.Err, cached.tail.Pending = err, false
+	} else {
+		cached.failures = 0
+		cached.tail = LogTail{Previous: cached.previous, Lines: lines}
+	}
+	var due time.Duration
+	switch {
+	case t.cache[key] != cached:
+		// The container restarted, or the tail was evicted, while it was being fetched.
+	case err != nil:
+		due = retryAfter(cached.failures)
+	case !cached.previous:
+		due = logRefreshInterval
+	}
+	t.mu.Unlock()
+
+	t.signal()
+	if due > 0 {
+		time.AfterFunc(due, t.signal)
+	}
+}
+
+func (t *LogTailer) signal() {
+	select {
+	case t.updates <- struct{}{}:
+	default:
+	}
+}
+
+func (t *LogTailer) evictLeastRecentlyUsed() {
+	var oldest *tailKey
+	for key, cached := range t.cache {
+		if oldest == nil || cached.used < t.cache[*oldest].used {
+			key := key
+			oldest = &key
+		}
+	}
+	delete(t.cache, *oldest)
+}
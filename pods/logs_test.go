@@ -0,0 +1,257 @@
+package pods
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// fakeLogSource returns a log line naming the container and instance asked for, and records every
+// fetch.
+type fakeLogSource struct {
+	mu      sync.Mutex
+	fetches []string
+	err     error
+}
+
+func (s *fakeLogSource) Tail(
+	namespace, pod, container string, previous bool, lines int64,
+) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fetch := fmt.Sprintf("%s/%s/%s previous=%t", namespace, pod, container, previous)
+	s.fetches = append(s.fetches, fetch)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []string{fetch}, nil
+}
+
+func (s *fakeLogSource) fetched() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.fetches...)
+}
+
+func (s *fakeLogSource) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// fakeClock is the time as seen by a LogTailer, which moves only when told to.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestTailer(source LogSource) (*LogTailer, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tailer := NewLogTailer(source, 10)
+	tailer.now = clock.Now
+	return tailer, clock
+}
+
+// fetchTail asks `tailer` for a tail that is due to be fetched, waits for the fetch to finish, and
+// returns the tail fetched.
+func fetchTail(t *testing.T, tailer *LogTailer, health Health, c ContainerHealth) LogTail {
+	t.Helper()
+	tailer.Tail(health, c)
+	select {
+	case <-tailer.Updates():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for the log to be fetched")
+	}
+	return tailer.Tail(health, c)
+}
+
+func pod(uid types.UID) Health {
+	return Health{Name: "nginx", Namespace: "default", UID: uid}
+}
+
+func container(state string, restarts int64) ContainerHealth {
+	return ContainerHealth{Name: "app", Type: App, State: state, RestartCount: restarts}
+}
+
+func TestTailSelectsPreviousInstance(t *testing.T) {
+	tests := []struct {
+		state        string
+		restarts     int64
+		wantPrevious bool
+	}{
+		// A container that never restarted has only its current log.
+		{"Waiting", 0, false},
+		{"Terminated", 0, false},
+		// A container that is crash looping is explained by the log of the instance that crashed.
+		{"Waiting", 3, true},
+		{"Terminated", 3, true},
+		// A container that is running again is failing (e.g., its probes) in its current instance.
+		{"Running", 3, false},
+	}
+	for _, test := range tests {
+		tailer, _ := newTestTailer(&fakeLogSource{})
+		tail := fetchTail(t, tailer, pod("u1"), container(test.state, test.restarts))
+
+		want := fmt.Sprintf("default/nginx/app previous=%t", test.wantPrevious)
+		if tail.Previous != test.wantPrevious || len(tail.Lines) != 1 || tail.Lines[0] != want {
+			t.Errorf("%s after %d restarts: got %+v, want the log of %s",
+				test.state, test.restarts, tail, want)
+		}
+	}
+}
+
+func TestTailIsPendingUntilFetched(t *testing.T) {
+	tailer, _ := newTestTailer(&fakeLogSource{})
+
+	if tail := tailer.Tail(pod("u1"), container("Waiting", 1)); !tail.Pending || !tail.Previous {
+		t.Errorf("Expected the tail to be pending before it is fetched, got %+v", tail)
+	}
+	if tail := fetchTail(t, tailer, pod("u1"), container("Waiting", 1)); tail.Pending {
+		t.Errorf("Expected the tail not to be pending once fetched, got %+v", tail)
+	}
+}
+
+func TestTailCachesPerInstance(t *testing.T) {
+	source := &fakeLogSource{}
+	tailer, clock := newTestTailer(source)
+
+	fetchTail(t, tailer, pod("u1"), container("Waiting", 1))
+	// The log of a previous instance never changes, so it is not fetched again, however much later.
+	clock.advance(time.Hour)
+	tailer.Tail(pod("u1"), container("Waiting", 1))
+	if fetches := source.fetched(); len(fetches) != 1 {
+		t.Fatalf("Expected the log to be fetched once for the same instance, got %v", fetches)
+	}
+
+	// The container restarted, so its previous instance is a different one.
+	fetchTail(t, tailer, pod("u1"), container("Waiting", 2))
+	// The container is running again, so its current log is wanted instead.
+	fetchTail(t, tailer, pod("u1"), container("Running", 2))
+	// A new Pod of the same name has logs of its own.
+	fetchTail(t, tailer, pod("u2"), container("Running", 2))
+
+	want := []string{
+		"default/nginx/app previous=true",
+		"default/nginx/app previous=true",
+		"default/nginx/app previous=false",
+		"default/nginx/app previous=false",
+	}
+	if fetches := source.fetched(); fmt.Sprint(fetches) != fmt.Sprint(want) {
+		t.Errorf("Expected fetches %v, got %v", want, fetches)
+	}
+}
+
+func TestTailRefreshesCurrentLog(t *testing.T) {
+	source := &fakeLogSource{}
+	tailer, clock := newTestTailer(source)
+
+	fetchTail(t, tailer, pod("u1"), container("Running", 0))
+	clock.advance(logRefreshInterval - time.Second)
+	tailer.Tail(pod("u1"), container("Running", 0))
+	if fetches := source.fetched(); len(fetches) != 1 {
+		t.Fatalf("Expected the current log not to be fetched again yet, got %v", fetches)
+	}
+
+	clock.advance(time.Second)
+	fetchTail(t, tailer, pod("u1"), container("Running", 0))
+	if fetches := source.fetched(); len(fetches) != 2 {
+		t.Errorf("Expected the current log to be fetched again, got %v", fetches)
+	}
+}
+
+func TestTailBacksOffAfterErrors(t *testing.T) {
+	source := &fakeLogSource{err: errors.New("pods/log is forbidden")}
+	tailer, clock := newTestTailer(source)
+
+	tail := fetchTail(t, tailer, pod("u1"), container("Waiting", 0))
+	if tail.Err == nil || tail.Pending {
+		t.Fatalf("Expected the error fetching the log, got %+v", tail)
+	}
+
+	// The error is kept until the first retry is due, and then for twice as long.
+	steps := []struct {
+		advance   time.Duration
+		wantFetch bool
+	}{
+		{0, false},
+		{minLogRetry - time.Second, false},
+		{time.Second, true},
+		{2*minLogRetry - time.Second, false},
+		{time.Second, true},
+	}
+	fetches := 1
+	for i, step := range steps {
+		clock.advance(step.advance)
+		if step.wantFetch {
+			fetchTail(t, tailer, pod("u1"), container("Waiting", 0))
+			fetches++
+		} else {
+			tailer.Tail(pod("u1"), container("Waiting", 0))
+		}
+		if got := source.fetched(); len(got) != fetches {
+			t.Fatalf("Step %d: expected %d fetches, got %v", i, fetches, got)
+		}
+	}
+
+	source.fail(nil)
+	clock.advance(4 * minLogRetry)
+	if tail := fetchTail(t, tailer, pod("u1"), container("Waiting", 0)); tail.Err != nil {
+		t.Errorf("Expected the log to be fetched once the error is gone, got %+v", tail)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, minLogRetry},
+		{2, 2 * minLogRetry},
+		{3, 4 * minLogRetry},
+		{100, maxLogRetry},
+	}
+	for _, test := range tests {
+		if got := retryAfter(test.failures); got != test.want {
+			t.Errorf("After %d failures: expected %v, got %v", test.failures, test.want, got)
+		}
+	}
+}
+
+func TestTailEvictsLeastRecentlyUsed(t *testing.T) {
+	tailer, _ := newTestTailer(&fakeLogSource{})
+
+	tailer.Tail(pod("first"), container("Running", 0))
+	for i := 0; i < maxCachedTails; i++ {
+		// Keep the first Pod's log in use.
+		tailer.Tail(pod("first"), container("Running", 0))
+		tailer.Tail(pod(types.UID(fmt.Sprint(i))), container("Running", 0))
+	}
+
+	tailer.mu.Lock()
+	defer tailer.mu.Unlock()
+	if len(tailer.cache) != maxCachedTails {
+		t.Fatalf("Expected %d cached tails, got %d", maxCachedTails, len(tailer.cache))
+	}
+	if _, cached := tailer.cache[tailKey{pod: "first", container: "app"}]; !cached {
+		t.Errorf("Expected the most recently used log to stay cached")
+	}
+	if _, cached := tailer.cache[tailKey{pod: "0", container: "app"}]; cached {
+		t.Errorf("Expected the least recently used log to be evicted")
+	}
+}
@@ -1,27 +1,60 @@
 package pods
 
 import (
-	"fmt"
+	"sort"
 
-	"github.com/fatih/color"
 	"github.com/pulumi/pulumi-kubernetes/provider/v4/pkg/openapi"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
 )
 
-var (
-	greenText    = color.New(color.FgGreen)
-	yellowText   = color.New(color.FgYellow)
-	cyanBoldText = color.New(color.FgCyan, color.Bold)
-	cyanText     = color.New(color.FgCyan)
-	redBoldText  = color.New(color.FgRed, color.Bold)
-)
+// Endpoint is a single network endpoint a Service directs traffic to, as reported by either a `v1`
+// Endpoints object or a `discovery.k8s.io/v1` EndpointSlice.
+type Endpoint struct {
+	// Name and Namespace of the Pod backing this endpoint. Empty if the endpoint has no `targetRef`
+	// (e.g., it was added to a manually-managed Endpoints object), or if it targets something other
+	// than a Pod.
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+
+	IP   string `json:"ip"`
+	Node string `json:"node,omitempty"`
+	Zone string `json:"zone,omitempty"`
+
+	// ZoneHints are the zones topology-aware routing prefers to serve this endpoint to.
+	ZoneHints []string `json:"zoneHints,omitempty"`
+	Ports     []Port   `json:"ports,omitempty"`
+
+	Ready       bool `json:"ready"`
+	Serving     bool `json:"serving"`
+	Terminating bool `json:"terminating"`
+
+	TargetRef *TargetRef `json:"targetRef,omitempty"`
+}
+
+// Port is a port exposed by an Endpoint.
+type Port struct {
+	Name     string `json:"name,omitempty"`
+	Port     int64  `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+// TargetRef is a reference to the object backing an Endpoint.
+type TargetRef struct {
+	Kind      string    `json:"kind"`
+	Namespace string    `json:"namespace,omitempty"`
+	Name      string    `json:"name"`
+	UID       types.UID `json:"uid,omitempty"`
+}
 
-func GetReady(o *unstructured.Unstructured) []string {
-	ready := []string{}
+// FromEndpoints returns every address of a `v1` Endpoints object. Addresses in `notReadyAddresses`
+// are returned as neither ready nor serving.
+func FromEndpoints(o *unstructured.Unstructured) []Endpoint {
+	endpoints := []Endpoint{}
 	subsetsI, _ := openapi.Pluck(o.Object, "subsets")
 	subsets, isSlice := subsetsI.([]interface{})
 	if !isSlice {
-		return ready
+		return endpoints
 	}
 
 	for _, subsetI := range subsets {
@@ -30,74 +63,190 @@ func GetReady(o *unstructured.Unstructured) []string {
 			continue
 		}
 
-		addressesI, _ := subset["addresses"]
-		addresses, isSlice := addressesI.([]interface{})
+		ports := parsePorts(subset["ports"])
+		for _, field := range []string{"addresses", "notReadyAddresses"} {
+			addressesI, _ := subset[field]
+			addresses, isSlice := addressesI.([]interface{})
+			if !isSlice {
+				continue
+			}
+
+			for _, addressI := range addresses {
+				address, isMap := addressI.(map[string]interface{})
+				if !isMap {
+					continue
+				}
+
+				ip, isString := address["ip"].(string)
+				if !isString {
+					continue
+				}
+
+				ready := field == "addresses"
+				endpoint := Endpoint{IP: ip, Ports: ports, Ready: ready, Serving: ready}
+				endpoint.Node, _ = address["nodeName"].(string)
+				endpoint.setTargetRef(address["targetRef"], o.GetNamespace())
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+	}
+
+	sortEndpoints(endpoints)
+	return endpoints
+}
+
+// FromEndpointSlices aggregates the endpoints of every `discovery.k8s.io/v1` EndpointSlice
+// belonging to a Service.
+func FromEndpointSlices(slices []*unstructured.Unstructured) []Endpoint {
+	endpoints := []Endpoint{}
+
+	// During updates, the EndpointSlice controller may briefly list an address in more than one
+	// slice.
+	seen := map[string]bool{}
+
+	for _, slice := range slices {
+		ports := parsePorts(slice.Object["ports"])
+
+		endpointsI, _ := openapi.Pluck(slice.Object, "endpoints")
+		sliceEndpoints, isSlice := endpointsI.([]interface{})
 		if !isSlice {
 			continue
 		}
 
-		for _, addressI := range addresses {
-			address, isMap := addressI.(map[string]interface{})
+		for _, endpointI := range sliceEndpoints {
+			rawEndpoint, isMap := endpointI.(map[string]interface{})
 			if !isMap {
 				continue
 			}
 
-			nameI, _ := openapi.Pluck(address, "targetRef", "name")
-			name, isString := nameI.(string)
+			addressesI, _ := rawEndpoint["addresses"]
+			addresses, isSlice := addressesI.([]interface{})
+			if !isSlice || len(addresses) == 0 {
+				continue
+			}
+			ip, isString := addresses[0].(string)
 			if !isString {
 				continue
 			}
 
-			ipI, _ := address["ip"]
-			ip, isString := ipI.(string)
-			if !isString {
+			endpoint := Endpoint{IP: ip, Ports: ports}
+			endpoint.Node, _ = rawEndpoint["nodeName"].(string)
+			endpoint.Zone, _ = rawEndpoint["zone"].(string)
+			endpoint.ZoneHints = zoneHints(rawEndpoint)
+			endpoint.setTargetRef(rawEndpoint["targetRef"], slice.GetNamespace())
+
+			key := endpoint.Namespace + "/" + endpoint.Name + "@" + ip
+			if seen[key] {
 				continue
 			}
-			ready = append(ready, fmt.Sprintf("[%s] %s @ %s", greenText.Sprint("Ready"), cyanText.Sprint(name), yellowText.Sprint(ip)))
+			seen[key] = true
+
+			// Per the EndpointSlice API, a nil `ready` or `serving` condition should be interpreted
+			// as true, and a nil `terminating` condition as false.
+			endpoint.Ready = conditionOr(rawEndpoint, "ready", true)
+			endpoint.Serving = conditionOr(rawEndpoint, "serving", endpoint.Ready)
+			endpoint.Terminating = conditionOr(rawEndpoint, "terminating", false)
+
+			endpoints = append(endpoints, endpoint)
 		}
 	}
-	return ready
+
+	sortEndpoints(endpoints)
+	return endpoints
 }
 
-func GetUnready(o *unstructured.Unstructured) []string {
-	ready := []string{}
-	subsetsI, _ := openapi.Pluck(o.Object, "subsets")
-	subsets, isSlice := subsetsI.([]interface{})
+func (e *Endpoint) setTargetRef(targetRefI interface{}, defaultNamespace string) {
+	targetRef, isMap := targetRefI.(map[string]interface{})
+	if !isMap {
+		return
+	}
+
+	ref := TargetRef{}
+	ref.Kind, _ = targetRef["kind"].(string)
+	ref.Name, _ = targetRef["name"].(string)
+	ref.Namespace, _ = targetRef["namespace"].(string)
+	if ref.Namespace == "" {
+		ref.Namespace = defaultNamespace
+	}
+	if uid, isString := targetRef["uid"].(string); isString {
+		ref.UID = types.UID(uid)
+	}
+	e.TargetRef = &ref
+
+	if ref.Kind == "Pod" || ref.Kind == "" {
+		e.Name = ref.Name
+		e.Namespace = ref.Namespace
+	}
+}
+
+func conditionOr(endpoint map[string]interface{}, condition string, dflt bool) bool {
+	valueI, _ := openapi.Pluck(endpoint, "conditions", condition)
+	value, isBool := valueI.(bool)
+	if !isBool {
+		return dflt
+	}
+	return value
+}
+
+func parsePorts(portsI interface{}) []Port {
+	ports := []Port{}
+	portList, isSlice := portsI.([]interface{})
 	if !isSlice {
-		return ready
+		return ports
 	}
 
-	for _, subsetI := range subsets {
-		subset, isMap := subsetI.(map[string]interface{})
+	for _, portI := range portList {
+		rawPort, isMap := portI.(map[string]interface{})
 		if !isMap {
 			continue
 		}
 
-		addressesI, _ := subset["notReadyAddresses"]
-		addresses, isSlice := addressesI.([]interface{})
-		if !isSlice {
+		port := Port{}
+		var isInt bool
+		if port.Port, isInt = rawPort["port"].(int64); !isInt {
 			continue
 		}
+		port.Name, _ = rawPort["name"].(string)
+		if port.Protocol, _ = rawPort["protocol"].(string); port.Protocol == "" {
+			port.Protocol = "TCP"
+		}
+		ports = append(ports, port)
+	}
+	return ports
+}
 
-		for _, addressI := range addresses {
-			address, isMap := addressI.(map[string]interface{})
-			if !isMap {
-				continue
-			}
-
-			nameI, _ := openapi.Pluck(address, "targetRef", "name")
-			name, isString := nameI.(string)
-			if !isString {
-				continue
-			}
+func zoneHints(endpoint map[string]interface{}) []string {
+	hints := []string{}
+	forZonesI, _ := openapi.Pluck(endpoint, "hints", "forZones")
+	forZones, isSlice := forZonesI.([]interface{})
+	if !isSlice {
+		return hints
+	}
 
-			ipI, _ := address["ip"]
-			ip, isString := ipI.(string)
-			if !isString {
-				continue
-			}
-			ready = append(ready, fmt.Sprintf("[%s] %s @ %s", redBoldText.Sprint("Not live"), cyanText.Sprint(name), yellowText.Sprint(ip)))
+	for _, zoneI := range forZones {
+		zone, isMap := zoneI.(map[string]interface{})
+		if !isMap {
+			continue
+		}
+		if name, isString := zone["name"].(string); isString {
+			hints = append(hints, name)
 		}
 	}
-	return ready
+	return hints
+}
+
+// sortEndpoints orders endpoints ready-first, then by Pod, then by IP.
+func sortEndpoints(endpoints []Endpoint) {
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		if endpoints[i].Ready != endpoints[j].Ready {
+			return endpoints[i].Ready
+		}
+		if endpoints[i].Namespace != endpoints[j].Namespace {
+			return endpoints[i].Namespace < endpoints[j].Namespace
+		}
+		if endpoints[i].Name != endpoints[j].Name {
+			return endpoints[i].Name < endpoints[j].Name
+		}
+		return endpoints[i].IP < endpoints[j].IP
+	})
 }
@@ -0,0 +1,204 @@
+package pods
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// parse parses the object `s`, failing the test if it is not one.
+func parse(t *testing.T, s string) *unstructured.Unstructured {
+	t.Helper()
+	o := &unstructured.Unstructured{}
+	if err := o.UnmarshalJSON([]byte(s)); err != nil {
+		t.Fatalf("Invalid test object %s: %v", s, err)
+	}
+	return o
+}
+
+// summary is the parts of an Endpoint the tests check, as `namespace/name@ip`, followed by a flag
+// for each of ready, serving, and terminating that is set.
+func summary(e Endpoint) string {
+	s := e.Namespace + "/" + e.Name + "@" + e.IP
+	for _, flag := range []struct {
+		set  bool
+		name string
+	}{{e.Ready, "ready"}, {e.Serving, "serving"}, {e.Terminating, "terminating"}} {
+		if flag.set {
+			s += " " + flag.name
+		}
+	}
+	return s
+}
+
+func summaries(endpoints []Endpoint) []string {
+	s := []string{}
+	for _, e := range endpoints {
+		s = append(s, summary(e))
+	}
+	return s
+}
+
+func TestFromEndpoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		subsets  string
+		want     []string
+		wantRefs []bool
+	}{
+		{name: "no subsets", subsets: `null`, want: []string{}},
+		{
+			name: "ready and not ready",
+			subsets: `[{
+				"addresses": [{"ip": "10.0.0.2", "targetRef": {"kind": "Pod", "name": "b"}}],
+				"notReadyAddresses": [{"ip": "10.0.0.1", "targetRef": {"kind": "Pod", "name": "a"}}]
+			}]`,
+			want:     []string{"default/b@10.0.0.2 ready serving", "default/a@10.0.0.1"},
+			wantRefs: []bool{true, true},
+		},
+		{
+			// E.g., a manually-managed Endpoints object of a Service without a selector.
+			name:     "no targetRef",
+			subsets:  `[{"addresses": [{"ip": "192.168.1.1"}]}]`,
+			want:     []string{"/@192.168.1.1 ready serving"},
+			wantRefs: []bool{false},
+		},
+		{
+			name: "targetRef to something other than a Pod",
+			subsets: `[{"addresses": [
+				{"ip": "10.0.0.1", "targetRef": {"kind": "Node", "name": "n", "namespace": ""}}
+			]}]`,
+			want:     []string{"/@10.0.0.1 ready serving"},
+			wantRefs: []bool{true},
+		},
+		{
+			name: "several subsets",
+			subsets: `[
+				{"addresses": [{"ip": "10.0.0.3", "targetRef": {"kind": "Pod", "name": "c"}}]},
+				{"addresses": [
+					{"ip": "10.0.0.9", "targetRef": {"kind": "Pod", "name": "a", "namespace": "other"}},
+					{"nodeName": "no-ip"}
+				]}
+			]`,
+			want:     []string{"default/c@10.0.0.3 ready serving", "other/a@10.0.0.9 ready serving"},
+			wantRefs: []bool{true, true},
+		},
+	}
+	for _, test := range tests {
+		o := parse(t, `{"apiVersion": "v1", "kind": "Endpoints",
+			"metadata": {"name": "svc", "namespace": "default"}, "subsets": `+test.subsets+`}`)
+		endpoints := FromEndpoints(o)
+		if got := summaries(endpoints); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: FromEndpoints = %q, want %q", test.name, got, test.want)
+			continue
+		}
+		for i, e := range endpoints {
+			if got := e.TargetRef != nil; got != test.wantRefs[i] {
+				t.Errorf("%s: endpoint %s has targetRef %v, want %v", test.name, e.IP, got,
+					test.wantRefs[i])
+			}
+		}
+	}
+}
+
+// endpointSlice is an EndpointSlice in `default` with the endpoints `endpoints`.
+func endpointSlice(t *testing.T, name, endpoints string) *unstructured.Unstructured {
+	t.Helper()
+	return parse(t, `{"apiVersion": "discovery.k8s.io/v1", "kind": "EndpointSlice",
+		"metadata": {"name": "`+name+`", "namespace": "default"},
+		"ports": [{"name": "http", "port": 80}], "endpoints": `+endpoints+`}`)
+}
+
+func TestFromEndpointSlices(t *testing.T) {
+	tests := []struct {
+		name   string
+		slices []string
+		want   []string
+	}{
+		{name: "no slices", want: []string{}},
+		{name: "no endpoints", slices: []string{`null`}, want: []string{}},
+		{
+			// Per the EndpointSlice API, nil `ready` and `serving` conditions mean true, and a nil
+			// `terminating` condition false.
+			name: "nil conditions",
+			slices: []string{`[
+				{"addresses": ["10.0.0.1"], "targetRef": {"kind": "Pod", "name": "a"}},
+				{"addresses": ["10.0.0.2"], "targetRef": {"kind": "Pod", "name": "b"},
+					"conditions": {}}
+			]`},
+			want: []string{"default/a@10.0.0.1 ready serving", "default/b@10.0.0.2 ready serving"},
+		},
+		{
+			// A nil `serving` condition is taken to be the same as `ready`.
+			name: "not ready, serving unknown",
+			slices: []string{`[{"addresses": ["10.0.0.1"], "targetRef": {"kind": "Pod", "name": "a"},
+				"conditions": {"ready": false}}]`},
+			want: []string{"default/a@10.0.0.1"},
+		},
+		{
+			name: "terminating and still serving",
+			slices: []string{`[{"addresses": ["10.0.0.1"], "targetRef": {"kind": "Pod", "name": "a"},
+				"conditions": {"ready": false, "serving": true, "terminating": true}}]`},
+			want: []string{"default/a@10.0.0.1 serving terminating"},
+		},
+		{
+			name:   "no targetRef",
+			slices: []string{`[{"addresses": ["192.168.1.1"]}, {"addresses": []}]`},
+			want:   []string{"/@192.168.1.1 ready serving"},
+		},
+		{
+			// During updates, the EndpointSlice controller may briefly list an address in more than
+			// one slice. The first is kept.
+			name: "duplicates across slices",
+			slices: []string{
+				`[{"addresses": ["10.0.0.1"], "targetRef": {"kind": "Pod", "name": "a"}}]`,
+				`[
+					{"addresses": ["10.0.0.1"], "targetRef": {"kind": "Pod", "name": "a"},
+						"conditions": {"ready": false}},
+					{"addresses": ["10.0.0.1"], "targetRef": {"kind": "Pod", "name": "b"}},
+					{"addresses": ["10.0.0.2"], "targetRef": {"kind": "Pod", "name": "a"}}
+				]`,
+			},
+			want: []string{
+				"default/a@10.0.0.1 ready serving",
+				"default/a@10.0.0.2 ready serving",
+				"default/b@10.0.0.1 ready serving",
+			},
+		},
+	}
+	for _, test := range tests {
+		slices := []*unstructured.Unstructured{}
+		for i, endpoints := range test.slices {
+			slices = append(slices, endpointSlice(t, string(rune('a'+i)), endpoints))
+		}
+		if got := summaries(FromEndpointSlices(slices)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: FromEndpointSlices = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSortEndpoints(t *testing.T) {
+	endpoints := []Endpoint{
+		{Namespace: "default", Name: "b", IP: "10.0.0.1"},
+		{Namespace: "default", Name: "a", IP: "10.0.0.9", Ready: true},
+		{IP: "10.0.0.5", Ready: true},
+		{Namespace: "default", Name: "a", IP: "10.0.0.2", Ready: true},
+		{Namespace: "default", Name: "a", IP: "10.0.0.3"},
+		{Namespace: "aaa", Name: "z", IP: "10.0.0.4", Ready: true},
+	}
+	sortEndpoints(endpoints)
+
+	// Ready endpoints come first, then each is ordered by Pod, then by IP.
+	want := []string{
+		"/@10.0.0.5 ready",
+		"aaa/z@10.0.0.4 ready",
+		"default/a@10.0.0.2 ready",
+		"default/a@10.0.0.9 ready",
+		"default/a@10.0.0.3",
+		"default/b@10.0.0.1",
+	}
+	if got := summaries(endpoints); !reflect.DeepEqual(got, want) {
+		t.Errorf("sortEndpoints = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+package print
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pulumi/kubespy/diff"
+)
+
+// Attributions prints the field managers responsible for each change, flagging fields whose owner
+// changed.
+func Attributions(w io.Writer, attributions []diff.Attribution) {
+	if len(attributions) == 0 {
+		return
+	}
+
+	whiteBoldText.Fprintln(w, "Changed by:")
+	for _, a := range attributions {
+		var op string
+		switch a.Op {
+		case "add":
+			op = greenText.Sprint("+")
+		case "remove":
+			op = redBoldText.Sprint("-")
+		default:
+			op = yellowText.Sprint("~")
+		}
+
+		fmt.Fprintf(w, "  %s %s: %s", op, cyanText.Sprint(a.Path.String()), managersString(a.Managers))
+		if a.Previous != nil {
+			fmt.Fprintf(w, " %s", redBoldText.Sprintf("[owner changed; was %s]", managersString(a.Previous)))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func managersString(managers []diff.Manager) string {
+	if len(managers) == 0 {
+		return faintText.Sprint("no field manager")
+	}
+	names := make([]string, len(managers))
+	for i, m := range managers {
+		names[i] = m.String()
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,73 @@
+package print
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pulumi/kubespy/trace"
+)
+
+// conditionsText prints a table of an object's status conditions, with the history of each.
+func conditionsText(w io.Writer, c *trace.Conditions) {
+	if c.Object == nil {
+		cyanBoldText.Fprintf(w, "Waiting for %s '%s/%s'\n", c.ObjectKind, c.Namespace, c.Name)
+		return
+	}
+
+	watchEventHeader(w, *c.Object)
+	switch {
+	case c.ObservedGeneration == nil:
+		faintText.Fprintf(w, "Generation %d (observed generation not reported)\n", c.Generation)
+	case c.GenerationLag() > 0:
+		yellowText.Fprintf(w, "Generation %d, observed %d (%d behind)\n", c.Generation,
+			*c.ObservedGeneration, c.GenerationLag())
+	default:
+		greenText.Fprintf(w, "Generation %d, observed\n", c.Generation)
+	}
+	fmt.Fprintln(w)
+
+	if len(c.Conditions) == 0 {
+		faintText.Fprintln(w, "No conditions")
+		return
+	}
+
+	rows := [][]cell{{
+		{"TYPE", whiteBoldText}, {"STATUS", whiteBoldText}, {"REASON", whiteBoldText},
+		{"SINCE", whiteBoldText}, {"HISTORY", whiteBoldText}, {"MESSAGE", whiteBoldText},
+	}}
+	for _, cond := range c.Conditions {
+		status := readinessCell(cond.Status)
+		since := cell{"-", faintText}
+		if !cond.LastTransitionTime.IsZero() {
+			since = cell{age(cond.LastTransitionTime), nil}
+		}
+		rows = append(rows, []cell{
+			{cond.Type, cyanText},
+			status,
+			{cond.Reason, nil},
+			since,
+			{sparkline(cond.Transitions), status.color},
+			{cond.Message, nil},
+		})
+	}
+	writeTable(w, rows)
+}
+
+// sparkline renders the statuses a condition has had, oldest first.
+func sparkline(transitions []trace.Transition) string {
+	var b strings.Builder
+	for _, t := range transitions {
+		switch t.Status {
+		case trueStatus:
+			b.WriteString(theme.Glyphs.SparkTrue)
+		case "False":
+			b.WriteString(theme.Glyphs.SparkFalse)
+		case "":
+			b.WriteString(" ")
+		default:
+			b.WriteString(theme.Glyphs.SparkUnknown)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,42 @@
+package print
+
+import (
+	"github.com/pulumi/kubespy/diff"
+)
+
+// ObjectDiff renders the changes from `before` to `after` as a JSON diff, colored according to the
+// current Theme. `modified` is false if the objects are identical, in which case `text` is empty.
+func ObjectDiff(before, after map[string]interface{}) (text string, modified bool, err error) {
+	return diff.Compute(before, after, diff.ASCII, themeDiffColors())
+}
+
+// PlainObjectDiff is like ObjectDiff, but never colors the diff. Added and removed lines are marked
+// with a leading `+` and `-`, respectively.
+func PlainObjectDiff(before, after map[string]interface{}) (text string, modified bool, err error) {
+	return diff.Compute(before, after, diff.ASCII, diff.Colors{})
+}
+
+// FormattedDiff renders the changes from `before` to `after` in `format`, colored according to the
+// current Theme if `format` is meant for people.
+func FormattedDiff(
+	before, after map[string]interface{}, format diff.Format,
+) (text string, modified bool, err error) {
+	return diff.Compute(before, after, format, themeDiffColors())
+}
+
+// KeyedDiff renders the changes from `before` to `after` to the part of the object at `within`,
+// with list elements matched up by the keys `schema` has for them, colored according to the current
+// Theme.
+func KeyedDiff(
+	before, after map[string]interface{}, schema diff.Schema, within diff.Path,
+) (text string, modified bool, err error) {
+	return diff.Keyed(before, after, schema, within, themeDiffColors())
+}
+
+// themeDiffColors are the colors of diffs in the current Theme, if output is colored.
+func themeDiffColors() diff.Colors {
+	if !ColorEnabled() {
+		return diff.Colors{}
+	}
+	return diffColors
+}
@@ -0,0 +1,64 @@
+package print
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/kubespy/trace"
+)
+
+// KubeEvent prints a Kubernetes Event as it happens, e.g., `EVENT Warning BackOff Pod
+// default/nginx-7c5ddbdf54-x8f2q: Back-off restarting failed container (x5)`.
+func KubeEvent(w io.Writer, e *trace.Event) {
+	fmt.Fprintf(w, "%s %s %s: %s\n", blueBoldText.Sprint("EVENT"),
+		eventTypeColor(e.Type).Sprintf("%s %s", e.Type, e.Reason),
+		cyanBoldText.Sprintf("%s %s", e.Regarding.Kind, objectID(e.Regarding)), eventMessage(e))
+}
+
+// eventsText prints a table of the most recent Kubernetes Events regarding a traced object.
+func eventsText(w io.Writer, events []*trace.Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	cyanBoldText.Fprintln(w, "EVENTS:")
+	rows := [][]cell{{
+		{"LAST SEEN", whiteBoldText}, {"TYPE", whiteBoldText}, {"REASON", whiteBoldText},
+		{"OBJECT", whiteBoldText}, {"MESSAGE", whiteBoldText},
+	}}
+	for _, e := range events {
+		rows = append(rows, []cell{
+			{age(e.LastTime), nil},
+			{e.Type, eventTypeColor(e.Type)},
+			{e.Reason, nil},
+			{fmt.Sprintf("%s/%s", e.Regarding.Kind, e.Regarding.Name), cyanText},
+			{eventMessage(e), nil},
+		})
+	}
+	writeTable(w, rows)
+}
+
+func eventTypeColor(eventType string) *color.Color {
+	if eventType == "Warning" {
+		return yellowBoldText
+	}
+	return faintText
+}
+
+// eventMessage is the message of an Event, along with how many times it has happened, if more than
+// once.
+func eventMessage(e *trace.Event) string {
+	if e.Count > 1 {
+		return fmt.Sprintf("%s (x%d)", e.Message, e.Count)
+	}
+	return e.Message
+}
+
+func objectID(o trace.Object) string {
+	if o.Namespace == "" {
+		return o.Name
+	}
+	return o.Namespace + "/" + o.Name
+}
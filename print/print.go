@@ -3,32 +3,20 @@ package print
 import (
 	"fmt"
 	"io"
-	"sort"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
-	"github.com/mbrlabs/uilive"
-	"github.com/pulumi/kubespy/k8sobject"
+	"github.com/pulumi/kubespy/diff"
 	"github.com/pulumi/kubespy/pods"
-	"github.com/pulumi/pulumi-kubernetes/provider/v4/pkg/openapi"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"github.com/pulumi/kubespy/trace"
 	k8sWatch "k8s.io/apimachinery/pkg/watch"
 )
 
 const (
-	v1Endpoints  = "v1/Endpoints"
-	v1Service    = "v1/Service"
-	v1Pod        = "v1/Pod"
-	deployment   = "Deployment"
-	v1ReplicaSet = "v1/ReplicaSet"
-
 	prefix = "\n       - "
 
-	deploymentRevisionKey = "deployment.kubernetes.io/revision"
-
-	trueStatus      = "True"
-	statusAvailable = "Available"
+	trueStatus = "True"
 )
 
 var (
@@ -40,497 +28,358 @@ var (
 	redBoldText    = color.New(color.FgRed, color.Bold)
 	whiteBoldText  = color.New(color.Bold)
 	yellowBoldText = color.New(color.FgYellow, color.Bold)
+	blueBoldText   = color.New(color.FgBlue, color.Bold)
 	faintText      = color.New(color.Faint)
+
+	diffColors = diff.Colors{
+		Added:    color.New(color.FgGreen),
+		Removed:  color.New(color.FgRed),
+		Location: color.New(color.FgCyan),
+		File:     color.New(color.Bold),
+	}
 )
 
+// Banner prints a message announcing what is being watched.
+func Banner(w io.Writer, fmtstr string, a ...interface{}) {
+	greenText.Fprintln(w, fmt.Sprintf(fmtstr, a...))
+}
+
+// Heading prints the heading of a change, e.g., the type of the watch event that caused it.
+func Heading(w io.Writer, heading string) {
+	blueBoldText.Fprintln(w, heading)
+}
+
+// ObjectHeading prints the heading of a change to one of many objects being watched, naming the
+// object, e.g., `MODIFIED ConfigMap default/app-config`.
+func ObjectHeading(w io.Writer, heading, kind, namespace, name string) {
+	id := name
+	if namespace != "" {
+		id = namespace + "/" + name
+	}
+	fmt.Fprintf(w, "%s %s\n", blueBoldText.Sprint(heading), cyanBoldText.Sprintf("%s %s", kind, id))
+}
+
+// Note prints an aside, e.g., the time at which a replayed change was recorded.
+func Note(w io.Writer, note string) {
+	faintText.Fprintln(w, note)
+}
+
+// NewObject prints the JSON of a newly-observed object.
+func NewObject(w io.Writer, json string) {
+	greenText.Fprintln(w, json)
+}
+
 // SuccessStatusEvent prints a message using the formatting of success status.
 func SuccessStatusEvent(w io.Writer, fmtstr string, a ...interface{}) {
-	fmt.Fprintf(w, "    ✅ %s\n", fmt.Sprintf(fmtstr, a...))
+	fmt.Fprintf(w, "    %s %s\n", theme.Glyphs.Success, fmt.Sprintf(fmtstr, a...))
 }
 
 // FailureStatusEvent prints a message using the formatting of a failure status.
 func FailureStatusEvent(w io.Writer, fmtstr string, a ...interface{}) {
-	fmt.Fprintf(w, "    ❌ %s\n", fmt.Sprintf(fmtstr, a...))
+	fmt.Fprintf(w, "    %s %s\n", theme.Glyphs.Failure, fmt.Sprintf(fmtstr, a...))
 }
 
 // PendingStatusEvent prints a message using the formatting of a pending status.
 func PendingStatusEvent(w io.Writer, fmtstr string, a ...interface{}) {
-	fmt.Fprintf(w, "    ⌛ %s\n", fmt.Sprintf(fmtstr, a...))
+	fmt.Fprintf(w, "    %s %s\n", theme.Glyphs.Pending, fmt.Sprintf(fmtstr, a...))
 }
 
-// ServiceWatchTable prints the status of a Service, as represented in a table.
-func ServiceWatchTable(w *uilive.Writer, table map[string][]k8sWatch.Event) {
-	var svcType string
-	if events, hasSvc := table[v1Service]; hasSvc {
-		o := events[0].Object.(*unstructured.Unstructured)
-		watchEventHeader(w, events[0].Type, o)
-
-		svcTypeI, _ := openapi.Pluck(o.Object, "spec", "type")
-		var isString bool
-		svcType, isString = svcTypeI.(string)
-		if !isString {
-			svcType = "ClusterIP"
-		}
-
-		switch svcType {
-		case "ClusterIP":
-			if eps, hasEndpoints := table[v1Endpoints]; hasEndpoints && eps[0].Type != k8sWatch.Deleted {
-				SuccessStatusEvent(w, "Successfully created Endpoints object '%s' to direct traffic to Pods",
-					cyanText.Sprint(o.GetName()))
-			} else {
-				FailureStatusEvent(w, "Waiting for Endpoints object to be created, to direct traffic to Pods")
-			}
-
-			clusterIPI, _ := openapi.Pluck(o.Object, "spec", "clusterIP")
-			if clusterIP, isString := clusterIPI.(string); isString && len(clusterIP) > 0 {
-				SuccessStatusEvent(w, "Successfully allocated a cluster-internal IP: %s",
-					cyanText.Sprint(o.GetName()))
-			} else {
-				FailureStatusEvent(w, "Waiting for cluster-internal IP to be allocated")
-			}
-		case "LoadBalancer":
-			if eps, hasEndpoints := table[v1Endpoints]; hasEndpoints && eps[0].Type != k8sWatch.Deleted {
-				SuccessStatusEvent(w, "Successfully created Endpoints object '%s' to direct traffic to Pods", o.GetName())
-			} else {
-				FailureStatusEvent(w, "Waiting for Endpoints object to be created, to direct traffic to Pods")
-			}
-
-			ingressesI, _ := openapi.Pluck(o.Object, "status", "loadBalancer", "ingress")
-			if ingresses, isMap := ingressesI.([]interface{}); isMap {
-				ips := []string{}
-				for _, ingressI := range ingresses {
-					if ingress, isMap := ingressI.(map[string]interface{}); isMap {
-						tmp := []string{}
-						ipI, _ := ingress["ip"]
-						ip, isString := ipI.(string)
-						if isString {
-							tmp = append(tmp, cyanText.Sprint(ip))
-						}
-
-						hostnameI, _ := ingress["hostname"]
-						hostname, isString := hostnameI.(string)
-						if isString {
-							tmp = append(tmp, cyanText.Sprint(hostname))
-						}
-
-						ips = append(ips, strings.Join(tmp, "/"))
-					}
-				}
+// WriteText writes a human-readable representation of a trace model to `w`.
+func WriteText(w io.Writer, m trace.Model) {
+	switch m := m.(type) {
+	case *trace.Service:
+		serviceText(w, m)
+	case *trace.Deployment:
+		deploymentText(w, m)
+	case *trace.Tree:
+		treeText(w, m)
+	case *trace.Conditions:
+		conditionsText(w, m)
+	default:
+		fmt.Fprintf(w, "Unknown trace kind '%s'\n", m.Kind())
+	}
+}
 
-				if len(ips) > 0 {
-					sort.Strings(ips)
-					li := prefix + strings.Join(ips, prefix)
-					SuccessStatusEvent(w, "Service allocated the following IPs/hostnames:%s", li)
-				}
-			} else {
-				FailureStatusEvent(w, "Waiting for public IP/host to be allocated")
-			}
+// serviceText prints the status of a Service, as represented in a table.
+func serviceText(w io.Writer, s *trace.Service) {
+	if s.Service == nil && len(s.EndpointObjects) == 0 {
+		cyanBoldText.Fprintf(w, "Waiting for Service '%s/%s'\n", s.Namespace, s.Name)
+		return
+	}
 
-		case "ExternalName":
-			externalNameI, _ := openapi.Pluck(o.Object, "spec", "externalName")
-			if externalName, isString := externalNameI.(string); isString && len(externalName) > 0 {
-				SuccessStatusEvent(w, "Service proxying to %s", cyanText.Sprint(externalName))
-			} else {
-				FailureStatusEvent(w, "Service not given a URI to proxy to in `.spec.externalName`")
-			}
+	if s.Service != nil {
+		watchEventHeader(w, *s.Service)
+		for _, c := range s.Checks {
+			checkText(w, c)
 		}
 	}
 
 	fmt.Fprintln(w)
 
-	if events, hasEPs := table[v1Endpoints]; hasEPs {
-		o := events[0].Object.(*unstructured.Unstructured)
-		watchEventHeader(w, events[0].Type, o)
-
-		ready := pods.GetReady(o)
-		sort.Strings(ready)
-		unready := pods.GetUnready(o)
-		sort.Strings(unready)
-		pods := append(ready, unready...)
-
-		if len(unready) > 0 {
-			li := prefix + strings.Join(pods, prefix)
-			FailureStatusEvent(w, "Directs traffic to the following live Pods:%s", li)
-		} else if len(ready) > 0 {
-			li := prefix + strings.Join(pods, prefix)
-			SuccessStatusEvent(w, "Directs traffic to the following live Pods:%s", li)
-		} else if len(unready) == 0 && len(ready) == 0 {
-			FailureStatusEvent(w, "Does not direct traffic to any Pods")
+	for _, o := range s.EndpointObjects {
+		watchEventHeader(w, o)
+	}
+	if s.EndpointsCheck != nil {
+		c := *s.EndpointsCheck
+		for _, endpoint := range s.Endpoints {
+			c.Items = append(c.Items, endpointString(endpoint))
 		}
-
-	} else if svcType != "ExternalName" {
-		fmt.Fprintln(w, "❌ Waiting for live Pods to be targeted by service")
+		checkText(w, c)
 	}
 
-	w.Flush()
+	eventsText(w, s.Events)
 }
 
-// DeploymentWatchTable prints the status of a Deployment, as represented in a table.
-func DeploymentWatchTable(w *uilive.Writer, table map[string][]k8sWatch.Event) {
-	const (
-		waitingForControllerCreate = "Waiting for controller to create Deployment"
-		rolloutNotStarted          = "Deployment has not begun to roll out the change"
-		appUnavailable             = "Deployment does not have minimum replicas (%d out of %d)"
-	)
-
-	var currentRevision int
-	if events, hasDepl := table[deployment]; hasDepl {
-		o := events[0].Object.(*unstructured.Unstructured)
-		var err error
-		currentRevision, err = parseRevision(o)
-		if err != nil {
-			FailureStatusEvent(w, waitingForControllerCreate)
-		}
-	}
-
-	// Get current ReplicaSet.
-	newReplicaSetAvailable := true
-	var currRepSet *unstructured.Unstructured
-	var currRepSetEventType k8sWatch.EventType
-	type repSet struct {
-		eventType k8sWatch.EventType
-		revision  int
-		replicas  int64
-		namespace string
-		name      string
-	}
-	var prevRepSet *unstructured.Unstructured
-	var prevRepSetSpec *repSet
-	if events, hasRs := table[v1ReplicaSet]; hasRs {
-		for _, e := range events {
-			rs := e.Object.(*unstructured.Unstructured)
-			repSetRevision, err := parseRevision(rs)
-			if err != nil {
-				continue
-			}
-			if currentRevision == repSetRevision {
-				currRepSet = rs
-				currRepSetEventType = e.Type
-			} else if e.Type != k8sWatch.Deleted {
-				replicasI, _ := openapi.Pluck(rs.Object, "status", "replicas")
-				replicas, _ := replicasI.(int64)
-
-				revision, err := parseRevision(rs)
-				if replicas > 0 && err == nil {
-					prevRepSet = rs
-					prevRepSetSpec = &repSet{
-						eventType: e.Type,
-						revision:  revision,
-						replicas:  replicas,
-						namespace: rs.GetNamespace(),
-						name:      rs.GetName()}
-				}
-			}
-		}
+// deploymentText prints the status of a Deployment, as represented in a table.
+func deploymentText(w io.Writer, d *trace.Deployment) {
+	if d.Deployment == nil && d.Current == nil && d.Previous == nil {
+		cyanBoldText.Fprintf(w, "Waiting for Deployment '%s/%s'\n", d.Namespace, d.Name)
+		return
 	}
 
 	// Display `Deployment` status.
-	if events, hasDepl := table[deployment]; hasDepl {
-		o := events[0].Object.(*unstructured.Unstructured)
-		watchEventHeader(w, events[0].Type, o)
-
-		specReplicasI, _ := openapi.Pluck(o.Object, "spec", "replicas")
-		specReplicas, isInt := specReplicasI.(int)
-		if !isInt {
-			specReplicas = 1
-		}
-
-		availableReplicasI, _ := openapi.Pluck(o.Object, "status", "availableReplicas")
-		availableReplicas, isInt := availableReplicasI.(int)
-		if !isInt {
-			specReplicas = 0
-		}
-
-		// Check Deployments conditions to see whether new ReplicaSet is available. If it is, we are
-		// successful.
-		conditionsI, _ := openapi.Pluck(o.Object, "status", "conditions")
-		conditions, isSlice := conditionsI.([]interface{})
-		if !isSlice {
-			FailureStatusEvent(w, appUnavailable, 0, specReplicas)
-			FailureStatusEvent(w, rolloutNotStarted)
-		} else {
-			whiteBoldText.Fprintf(w, "    Rolling out Deployment revision %d\n", currentRevision)
-
-			var isProgressing bool
-			var progressingReason string
-
-			var deploymentAvailable bool
-			var availableReason string
-
-			var rolloutSuccessful bool
-
-			// Success occurs when the ReplicaSet of the `currentGeneration` is marked as available, and
-			// when the deployment is available.
-			for _, rawCondition := range conditions {
-				condition, isMap := rawCondition.(map[string]interface{})
-				if !isMap {
-					continue
-				}
-
-				if condition["type"] == "Progressing" {
-					isProgressing = condition["status"] == trueStatus
-
-					reasonI, _ := condition["reason"]
-					reason, isString := reasonI.(string)
-					if !isString {
-						continue
-					}
-					messageI, _ := condition["message"]
-					message, isString := messageI.(string)
-					if !isString {
-						continue
-					}
-					progressingReason = fmt.Sprintf("[%s] %s", reason, message)
-
-					rolloutSuccessful = condition["reason"] == "NewReplicaSetAvailable" && newReplicaSetAvailable
-				}
-
-				if condition["type"] == statusAvailable {
-					deploymentAvailable = condition["status"] == trueStatus
-					reasonI, _ := condition["reason"]
-					reason, isString := reasonI.(string)
-					if !isString {
-						continue
-					}
-					messageI, _ := condition["message"]
-					message, isString := messageI.(string)
-					if !isString {
-						continue
-					}
-					availableReason = fmt.Sprintf("[%s] %s", reason, message)
-				}
-			}
-
-			if !deploymentAvailable {
-				FailureStatusEvent(w, "Deployment is failing; %d out of %d Pods are available: %s",
-					availableReplicas, specReplicas, availableReason)
-			} else {
-				SuccessStatusEvent(w, "Deployment is currently available")
-			}
-
-			if !isProgressing {
-				FailureStatusEvent(w, "Rollout has failed; controller is no longer rolling forward: %s",
-					progressingReason)
-			} else if rolloutSuccessful {
-				SuccessStatusEvent(w, "Rollout successful: new ReplicaSet marked 'available'")
-			} else {
-				PendingStatusEvent(w, "Rollout proceeding: %s", progressingReason)
-			}
-		}
+	if d.Deployment != nil {
+		watchEventHeader(w, *d.Deployment)
+	}
+	if d.RollingOut {
+		whiteBoldText.Fprintf(w, "    Rolling out Deployment revision %d\n", d.Revision)
+	}
+	for _, c := range d.Checks {
+		checkText(w, c)
 	}
 
 	fmt.Fprintln(w)
 
 	// Display `ReplicaSet` status.
-	if currRepSet != nil {
+	if rs := d.Current; rs != nil {
 		cyanBoldText.Fprintln(w, "ROLLOUT STATUS:")
-		fmt.Fprintf(w, "- [%s | Revision %d] ", yellowBoldText.Sprint("Current rollout"), currentRevision)
+		fmt.Fprintf(w, "- [%s | Revision %d] ", yellowBoldText.Sprint("Current rollout"), rs.Revision)
+		fmt.Fprintf(w, "[%s]  %s/%s\n", eventTypeString(rs.EventType), rs.Namespace, rs.Name)
 
-		specReplicasI, _ := openapi.Pluck(currRepSet.Object, "spec", "replicas")
-		specReplicas, isInt := specReplicasI.(int64)
-		if !isInt {
-			specReplicas = 1
-		}
-
-		availableReplicasI, _ := openapi.Pluck(currRepSet.Object, "status", "availableReplicas")
-		availableReplicas, isInt := availableReplicasI.(int64)
-		if !isInt {
-			availableReplicas = 0
-		}
-
-		currReplicaSetStatus(w, currRepSetEventType, currentRevision, currRepSet)
-		if availableReplicas < specReplicas {
-			PendingStatusEvent(w,
-				"Waiting for ReplicaSet to attain minimum available Pods (%d available of a %d minimum)",
-				availableReplicas, specReplicas)
-		} else {
-			SuccessStatusEvent(w,
-				"ReplicaSet is available [%d Pods available of a %d minimum]",
-				availableReplicas, specReplicas)
+		for _, c := range rs.Checks {
+			checkText(w, c)
 		}
 
 		printPodStatus(w,
-			func(w io.Writer, f string, a ...interface{}) { fmt.Fprintf(w, f, a...) }, currRepSet, table)
+			func(w io.Writer, f string, a ...interface{}) { fmt.Fprintf(w, f, a...) }, rs.Pods)
 	} else {
-		fmt.Fprintln(w, "⌛ Waiting for Deployment controller to create ReplicaSet")
+		fmt.Fprintf(w, "%s Waiting for Deployment controller to create ReplicaSet\n", theme.Glyphs.Pending)
 	}
 
-	if prevRepSetSpec != nil {
+	if rs := d.Previous; rs != nil {
 		fmt.Fprintln(w)
 
 		faintText.Fprintf(w, "- [%s", whiteBoldText.Sprint("Previous ReplicaSet"))
-		faintText.Fprintf(w, " | Revision %d] [%s", prevRepSetSpec.revision,
-			greenText.Sprint(prevRepSetSpec.eventType))
-		faintText.Fprintf(w, "]  %s/%s\n", prevRepSetSpec.namespace, prevRepSetSpec.name)
-		faintText.Fprintf(w, "    ⌛ Waiting for ReplicaSet to scale to 0 Pods (%d currently exist)\n",
-			prevRepSetSpec.replicas)
-
-		printPodStatus(w, faintText.FprintfFunc(), prevRepSet, table)
+		faintText.Fprintf(w, " | Revision %d] [%s", rs.Revision, greenText.Sprint(rs.EventType))
+		faintText.Fprintf(w, "]  %s/%s\n", rs.Namespace, rs.Name)
+		for _, c := range rs.Checks {
+			faintText.Fprint(w, checkString(c))
+		}
 
+		printPodStatus(w, faintText.FprintfFunc(), rs.Pods)
 	}
 
-	w.Flush()
+	eventsText(w, d.Events)
 }
 
-func printPodStatus(w io.Writer, fprintf func(w io.Writer, f string, a ...interface{}),
-	rs *unstructured.Unstructured, table map[string][]k8sWatch.Event) {
-	if podEvents, exists := table[v1Pod]; exists {
-		for _, e := range podEvents {
-			pod := e.Object.(*unstructured.Unstructured)
-			if !k8sobject.OwnedBy(pod, rs.GetAPIVersion(), rs.GetKind(), rs.GetName()) {
-				continue
-			}
-
-			conditions := k8sobject.PodConditions(pod)
-			for _, conditionI := range conditions {
-				condition, isMap := conditionI.(map[string]interface{})
-				if !isMap {
-					continue
-				}
+// checkText prints a check using the formatting of its status.
+func checkText(w io.Writer, c trace.Check) {
+	fmt.Fprint(w, checkString(c))
+}
 
-				if condition["type"] == "PodScheduled" {
-					if condition["status"] != trueStatus {
-						reason, message := errorFromCondition(condition)
-						printPodContainerError(w, fprintf, pod, reason, message)
-					}
-				}
+func checkString(c trace.Check) string {
+	args := make([]interface{}, len(c.Args))
+	for i, arg := range c.Args {
+		if highlight, isHighlight := arg.(trace.Highlight); isHighlight {
+			args[i] = cyanText.Sprint(string(highlight))
+		} else {
+			args[i] = arg
+		}
+	}
 
-				if condition["type"] == "Initialized" {
-					if condition["status"] != trueStatus {
-						reason, message := errorFromCondition(condition)
-						printPodContainerError(w, fprintf, pod, reason, message)
-					}
-				}
+	message := fmt.Sprintf(c.Format, args...)
+	if len(c.Items) > 0 {
+		message += prefix + strings.Join(c.Items, prefix)
+	}
 
-				if condition["type"] == "Ready" {
-					if condition["status"] != trueStatus {
-						reason, message := errorFromCondition(condition)
-						printPodContainerError(w, fprintf, pod, reason, message)
-					} else {
-						fprintf(w, "       - [%s", greenText.Sprint("Ready"))
-						fprintf(w, "] %s\n", cyanText.Sprint(pod.GetName()))
-					}
-				}
-			}
+	var b strings.Builder
+	switch c.Status {
+	case trace.Success:
+		SuccessStatusEvent(&b, "%s", message)
+	case trace.Pending:
+		PendingStatusEvent(&b, "%s", message)
+	default:
+		FailureStatusEvent(&b, "%s", message)
+	}
+	return b.String()
+}
 
-			// Collect the errors from any containers that are failing.
-			containerStatuses := k8sobject.PodContainerStatuses(pod)
-			for _, rawContainerStatus := range containerStatuses {
-				containerStatus, isMap := rawContainerStatus.(map[string]interface{})
-				if !isMap || containerStatus["ready"] == true {
-					continue
-				}
+func endpointString(e pods.Endpoint) string {
+	var state string
+	switch {
+	case e.Ready:
+		state = greenText.Sprint("Ready")
+	case e.Terminating && e.Serving:
+		state = yellowText.Sprint("Terminating, still serving")
+	case e.Terminating:
+		state = redBoldText.Sprint("Terminating")
+	default:
+		state = redBoldText.Sprint("Not live")
+	}
 
-				// Process container that's waiting.
-				rawWaiting, isWaiting := openapi.Pluck(containerStatus, "state", "waiting")
-				waiting, isMap := rawWaiting.(map[string]interface{})
-				if isWaiting && rawWaiting != nil && isMap {
-					reason, message := checkWaitingContainer(waiting)
-					printPodContainerError(w, fprintf, pod, reason, message)
-				}
+	name := e.Name
+	if name == "" && e.TargetRef != nil {
+		name = fmt.Sprintf("%s/%s", e.TargetRef.Kind, e.TargetRef.Name)
+	} else if name == "" {
+		name = "<no targetRef>"
+	}
 
-				// Process container that's terminated.
-				rawTerminated, isTerminated := openapi.Pluck(containerStatus, "state", "terminated")
-				terminated, isMap := rawTerminated.(map[string]interface{})
-				if isTerminated && rawTerminated != nil && isMap {
-					reason, message := checkTerminatedContainer(terminated)
-					printPodContainerError(w, fprintf, pod, reason, message)
-				}
+	details := []string{}
+	if len(e.Ports) > 0 {
+		ports := []string{}
+		for _, port := range e.Ports {
+			if port.Name != "" {
+				ports = append(ports, fmt.Sprintf("%s:%d/%s", port.Name, port.Port, port.Protocol))
+			} else {
+				ports = append(ports, fmt.Sprintf("%d/%s", port.Port, port.Protocol))
 			}
+		}
+		details = append(details, strings.Join(ports, ", "))
+	}
+	if e.Node != "" {
+		details = append(details, "node "+e.Node)
+	}
+	if e.Zone != "" {
+		details = append(details, "zone "+e.Zone)
+	}
+	if len(e.ZoneHints) > 0 {
+		details = append(details, "hints: "+strings.Join(e.ZoneHints, ", "))
+	}
 
-			// Exhausted our knowledge of possible error states for Pods. Return.
+	var suffix string
+	if len(details) > 0 {
+		suffix = fmt.Sprintf(" (%s)", strings.Join(details, "; "))
+	}
+	return fmt.Sprintf("[%s] %s @ %s%s", state, cyanText.Sprint(name), yellowText.Sprint(e.IP), suffix)
+}
+
+func printPodStatus(w io.Writer, fprintf func(w io.Writer, f string, a ...interface{}), pods []trace.Pod) {
+	for _, pod := range pods {
+		if pod.Ready {
+			fprintf(w, "       - [%s", greenText.Sprint("Ready"))
+			fprintf(w, "] %s\n", cyanText.Sprint(pod.Name))
 		}
+
+		for _, problem := range pod.Conditions {
+			printPodContainerError(w, fprintf, pod.Name, problem.Reason, problem.Message)
+		}
+
+		printContainerHealth(w, fprintf, pod)
 	}
 }
 
 func printPodContainerError(w io.Writer, fprintf func(w io.Writer, f string, a ...interface{}),
-	pod *unstructured.Unstructured, reason,
+	podName, reason,
 	message string) {
 	if reason == "" || message == "" {
 		return
 	}
 	fprintf(w, "       - [%s", redBoldText.Sprint(reason))
-	fprintf(w, "] %s", cyanText.Sprint(pod.GetName()))
+	fprintf(w, "] %s", cyanText.Sprint(podName))
 	fprintf(w, " %s\n", message)
 }
 
-func errorFromCondition(condition map[string]interface{}) (string, string) {
-	reasonI, _ := condition["reason"]
-	reason, isString := reasonI.(string)
-	if !isString {
-		return "", ""
-	}
-	messageI, _ := condition["message"]
-	message, isString := messageI.(string)
-	if !isString {
-		return "", ""
-	}
+// printContainerHealth prints a compact line for every container in a Pod that is failing, is not
+// ready yet, or has restarted, along with the progress of the Pod's init containers and, if they
+// were fetched, the log tails of failing containers.
+func printContainerHealth(w io.Writer, fprintf func(w io.Writer, f string, a ...interface{}),
+	pod trace.Pod) {
+	const indent = "           "
 
-	return reason, message
-}
-
-func checkWaitingContainer(waiting map[string]interface{}) (string, string) {
-	rawReason, hasReason := waiting["reason"]
-	reason, isString := rawReason.(string)
-	if !hasReason || !isString || reason == "" || reason == "ContainerCreating" {
-		return "", ""
+	if pod.InitTotal > 0 && pod.InitCompleted < pod.InitTotal {
+		fprintf(w, "%sinit containers: %d/%d complete\n", indent, pod.InitCompleted, pod.InitTotal)
 	}
 
-	rawMessage, hasMessage := waiting["message"]
-	message, isString := rawMessage.(string)
-	if !hasMessage || !isString {
-		return "", ""
-	}
+	for _, c := range pod.Containers {
+		if !c.Failing() && c.RestartCount == 0 && c.NotReady == "" {
+			continue
+		}
 
-	// Image pull error has a bunch of useless junk at the beginning of the error message. Try to
-	// remove it.
-	imagePullJunk := "rpc error: code = Unknown desc = Error response from daemon: "
-	message = strings.TrimPrefix(message, imagePullJunk)
+		details := []string{}
+		if c.Problem != nil {
+			problem := fmt.Sprintf("[%s]", redBoldText.Sprint(c.Problem.Reason))
+			if c.Problem.Reason == "CrashLoopBackOff" {
+				// The kubelet's message just repeats the back-off delay, which we report below.
+				if !c.NextRestart.IsZero() {
+					problem += fmt.Sprintf(" restarting in %s", roundDuration(time.Until(c.NextRestart)))
+				} else if c.BackOff > 0 {
+					problem += fmt.Sprintf(" back-off %s", c.BackOff)
+				}
+			} else if c.Problem.Message != "" {
+				problem += " " + c.Problem.Message
+			}
+			details = append(details, problem)
+		} else if c.NotReady != "" {
+			details = append(details, faintText.Sprintf("not ready yet: %s", c.NotReady))
+		}
+		if c.RestartCount > 0 {
+			details = append(details, fmt.Sprintf("%d restarts", c.RestartCount))
+		}
+		if t := c.LastTermination; t != nil && c.RestartCount > 0 {
+			last := fmt.Sprintf("last exit: %s (code %d)", yellowText.Sprint(t.Reason), t.ExitCode)
+			if !t.FinishedAt.IsZero() {
+				last += fmt.Sprintf(" %s ago", roundDuration(time.Since(t.FinishedAt)))
+			}
+			details = append(details, last)
+		}
 
-	return reason, message
+		fprintf(w, "%s%s %s: %s\n", indent, c.Type, cyanText.Sprint(c.Name), strings.Join(details, "; "))
+
+		if tail, hasLogs := pod.Logs[c.Name]; hasLogs {
+			printLogTail(w, indent+"  ", tail)
+		}
+	}
 }
 
-func checkTerminatedContainer(terminated map[string]interface{}) (string, string) {
-	reasonI, _ := terminated["reason"]
-	reason, isString := reasonI.(string)
-	if !isString || reason == "" {
-		return "", ""
+func printLogTail(w io.Writer, indent string, tail pods.LogTail) {
+	source := "current"
+	if tail.Previous {
+		source = "previous"
 	}
 
-	messageI, _ := terminated["message"]
-	message, isString := messageI.(string)
-	if !isString {
-		message = fmt.Sprintf("Container completed with exit code %d", terminated["exitCode"])
+	if tail.Pending {
+		faintText.Fprintf(w, "%sfetching %s logs...\n", indent, source)
+		return
+	} else if tail.Err != nil {
+		faintText.Fprintf(w, "%sunable to fetch %s logs: %v\n", indent, source, tail.Err)
+		return
+	} else if len(tail.Lines) == 0 {
+		faintText.Fprintf(w, "%sno %s logs\n", indent, source)
+		return
 	}
 
-	return reason, message
+	faintText.Fprintf(w, "%s%s logs:\n", indent, source)
+	for _, line := range tail.Lines {
+		faintText.Fprintf(w, "%s%s %s\n", indent, theme.Glyphs.Gutter, line)
+	}
 }
 
-func watchEventHeader(w io.Writer, eventType k8sWatch.EventType, o *unstructured.Unstructured) {
-	var eventTypeS string
-	if eventType == k8sWatch.Deleted {
-		eventTypeS = redBoldText.Sprint(eventType)
-	} else {
-		eventTypeS = greenText.Sprint(eventType)
+// roundDuration rounds `d` to the nearest second, and clamps it to zero.
+func roundDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
 	}
-	apiType := cyanBoldText.Sprintf("%s/%s", o.GetAPIVersion(), o.GetKind())
-	fmt.Fprintf(w, "[%s %s]  %s/%s\n", eventTypeS, apiType, o.GetNamespace(), o.GetName())
+	return d.Round(time.Second)
 }
 
-func currReplicaSetStatus(
-	w io.Writer, eventType k8sWatch.EventType, revision int, o *unstructured.Unstructured,
-) {
-	var eventTypeS string
-	if eventType == k8sWatch.Deleted {
-		eventTypeS = redBoldText.Sprint(eventType)
-	} else {
-		eventTypeS = greenText.Sprint(eventType)
-	}
-	fmt.Fprintf(w, "[%s]  %s/%s\n", eventTypeS, o.GetNamespace(), o.GetName())
+func watchEventHeader(w io.Writer, o trace.Object) {
+	apiType := cyanBoldText.Sprintf("%s/%s", o.APIVersion, o.Kind)
+	fmt.Fprintf(w, "[%s %s]  %s/%s\n", eventTypeString(o.EventType), apiType, o.Namespace, o.Name)
 }
 
-func parseRevision(o *unstructured.Unstructured) (int, error) {
-	revisionI, _ := openapi.Pluck(o.Object, "metadata", "annotations", deploymentRevisionKey)
-	revisionS, _ := revisionI.(string)
-	return strconv.Atoi(revisionS)
+func eventTypeString(eventType k8sWatch.EventType) string {
+	if eventType == k8sWatch.Deleted {
+		return redBoldText.Sprint(eventType)
+	}
+	return greenText.Sprint(eventType)
 }
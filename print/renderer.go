@@ -0,0 +1,139 @@
+package print
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/mbrlabs/uilive"
+	"github.com/pulumi/kubespy/trace"
+)
+
+// Renderer displays the models produced by a trace, each time the trace changes.
+type Renderer interface {
+	// Render displays the latest state of a trace.
+	Render(m trace.Model) error
+
+	// Close flushes any buffered output. The Renderer must not be used afterwards.
+	Close() error
+}
+
+// NewRenderer picks a Renderer appropriate for `out`: one that redraws the trace in place if `out`
+// is a terminal, and one that appends plain text otherwise (e.g., in CI logs, or when piped to a
+// file).
+func NewRenderer(out *os.File) Renderer {
+	if isatty.IsTerminal(out.Fd()) || isatty.IsCygwinTerminal(out.Fd()) {
+		return NewLiveRenderer(out)
+	}
+	return NewPlainRenderer(out)
+}
+
+// NewLiveRenderer creates a Renderer that redraws the trace in place on a terminal.
+func NewLiveRenderer(out io.Writer) Renderer {
+	writer := uilive.New()
+	writer.Out = out
+	writer.RefreshInterval = time.Minute * 1
+	writer.Start() // Start listening for updates, render.
+	return &liveRenderer{writer: writer}
+}
+
+// IsLive reports whether `r` redraws the trace in place, in which case it is worth re-rendering
+// periodically, so that relative times stay current. Other Renderers would append a snapshot each
+// time.
+func IsLive(r Renderer) bool {
+	_, isLive := r.(*liveRenderer)
+	return isLive
+}
+
+type liveRenderer struct {
+	writer *uilive.Writer
+}
+
+func (r *liveRenderer) Render(m trace.Model) error {
+	WriteText(r.writer, m)
+	return r.writer.Flush()
+}
+
+func (r *liveRenderer) Close() error {
+	r.writer.Stop() // Flush buffers, stop rendering.
+	return nil
+}
+
+// NewPlainRenderer creates a Renderer that appends a timestamped snapshot of the trace to `out`
+// each time it changes. It never emits cursor-movement escape sequences.
+func NewPlainRenderer(out io.Writer) Renderer {
+	return &plainRenderer{out: out}
+}
+
+type plainRenderer struct {
+	out  io.Writer
+	last []byte
+}
+
+func (r *plainRenderer) Render(m trace.Model) error {
+	var b bytes.Buffer
+	WriteText(&b, m)
+	if bytes.Equal(b.Bytes(), r.last) {
+		return nil
+	}
+	r.last = b.Bytes()
+
+	if _, err := faintText.Fprintf(r.out, "--- %s ---\n", time.Now().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	_, err := r.out.Write(b.Bytes())
+	return err
+}
+
+func (r *plainRenderer) Close() error {
+	return nil
+}
+
+// Document is a single state of a trace, as emitted by the structured Renderer.
+type Document struct {
+	Time    time.Time     `json:"time"`
+	Kind    string        `json:"kind"`
+	Verdict trace.Verdict `json:"verdict"`
+	Trace   trace.Model   `json:"trace"`
+}
+
+// NewStructuredRenderer creates a Renderer that writes each state of the trace to `out` as a JSON
+// Document, one per line (i.e., NDJSON). A Document is written only when the trace changes.
+func NewStructuredRenderer(out io.Writer) Renderer {
+	return &structuredRenderer{out: out}
+}
+
+type structuredRenderer struct {
+	out  io.Writer
+	last []byte
+}
+
+func (r *structuredRenderer) Render(m trace.Model) error {
+	model, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(model, r.last) {
+		return nil
+	}
+	r.last = model
+
+	doc, err := json.Marshal(Document{
+		Time:    time.Now().UTC(),
+		Kind:    m.Kind(),
+		Verdict: m.Verdict(),
+		Trace:   m,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.out.Write(append(doc, '\n'))
+	return err
+}
+
+func (r *structuredRenderer) Close() error {
+	return nil
+}
@@ -0,0 +1,66 @@
+package print
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pulumi/kubespy/trace"
+)
+
+// WriteMarkdownSummary writes a concise Markdown summary of a rollout to `w`, suitable for posting
+// to a pull request or a chat channel.
+func WriteMarkdownSummary(w io.Writer, s *trace.RolloutSummary) {
+	id := fmt.Sprintf("`%s/%s`", s.Namespace, s.Name)
+	switch s.Verdict {
+	case trace.Succeeded:
+		fmt.Fprintf(w, "### %s Deployment %s rolled out revision %d\n\n", theme.Glyphs.Success, id, s.Revision)
+	case trace.Failed:
+		fmt.Fprintf(w, "### %s Deployment %s failed to roll out revision %d\n\n", theme.Glyphs.Failure, id,
+			s.Revision)
+	case trace.Waiting:
+		fmt.Fprintf(w, "### %s Deployment %s was not observed\n\n", theme.Glyphs.Pending, id)
+		return
+	default:
+		fmt.Fprintf(w, "### %s Deployment %s is still rolling out revision %d\n\n", theme.Glyphs.Pending,
+			id, s.Revision)
+	}
+
+	duration := "unknown"
+	if d := s.Duration(); d > 0 {
+		duration = d.Round(time.Second).String()
+	}
+	fmt.Fprintln(w, "| | |")
+	fmt.Fprintln(w, "|---|---|")
+	fmt.Fprintf(w, "| Revision | %d |\n", s.Revision)
+	fmt.Fprintf(w, "| Duration | %s |\n", duration)
+	fmt.Fprintf(w, "| Max unavailable | %d of %d Pods |\n", s.MaxUnavailable, s.SpecReplicas)
+	fmt.Fprintf(w, "| Failed Pods | %d |\n", len(s.FailedPods))
+
+	if len(s.FailedPods) > 0 {
+		fmt.Fprintln(w, "\n**Failed Pods**")
+		for _, pod := range s.FailedPods {
+			fmt.Fprintf(w, "- `%s`", pod.Name)
+			if pod.Restarts > 0 {
+				fmt.Fprintf(w, " (%d restarts)", pod.Restarts)
+			}
+			fmt.Fprintln(w)
+			for _, reason := range pod.Reasons {
+				fmt.Fprintf(w, "  - %s\n", markdownLine(reason))
+			}
+		}
+	}
+
+	if len(s.Conditions) > 0 {
+		fmt.Fprintln(w, "\n**Final conditions**")
+		for _, condition := range s.Conditions {
+			fmt.Fprintf(w, "- %s\n", markdownLine(condition))
+		}
+	}
+}
+
+// markdownLine keeps multi-line messages (e.g., from the kubelet) from breaking out of a list item.
+func markdownLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
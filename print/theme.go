@@ -0,0 +1,136 @@
+package print
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/kubespy/diff"
+)
+
+// ColorMode controls whether output is colored.
+type ColorMode string
+
+const (
+	// ColorAuto colors output only if it is a terminal and `NO_COLOR` is not set.
+	ColorAuto ColorMode = "auto"
+	// ColorAlways colors output unconditionally, e.g., when piping to `less -R`.
+	ColorAlways ColorMode = "always"
+	// ColorNever never colors output.
+	ColorNever ColorMode = "never"
+)
+
+// Palette is a set of colors chosen to be legible on a terminal's background.
+type Palette string
+
+const (
+	// PaletteDark is legible on dark backgrounds. It is the default.
+	PaletteDark Palette = "dark"
+	// PaletteLight is legible on light backgrounds, on which yellow and cyan text is hard to read.
+	PaletteLight Palette = "light"
+)
+
+// Glyphs are the symbols used to mark the status of checks and draw trees.
+type Glyphs struct {
+	Success string
+	Failure string
+	Pending string
+
+	// Branch and LastBranch connect a child to its parent in a tree, and Pipe continues a branch
+	// past a child's descendants.
+	Branch     string
+	LastBranch string
+	Pipe       string
+
+	// Gutter precedes each line of a log tail.
+	Gutter string
+
+	// SparkTrue, SparkFalse, and SparkUnknown mark each status a condition has had in its history.
+	SparkTrue    string
+	SparkFalse   string
+	SparkUnknown string
+}
+
+var (
+	// EmojiGlyphs are the default Glyphs.
+	EmojiGlyphs = Glyphs{
+		Success: "✅", Failure: "❌", Pending: "⌛",
+		Branch: "├─", LastBranch: "└─", Pipe: "│ ",
+		Gutter:    "│",
+		SparkTrue: "▇", SparkFalse: "▁", SparkUnknown: "▄",
+	}
+
+	// ASCIIGlyphs are Glyphs for terminals that can't display emoji, for screen readers, and for log
+	// aggregators that mangle non-ASCII text.
+	ASCIIGlyphs = Glyphs{
+		Success: "[ok]", Failure: "[FAIL]", Pending: "[wait]",
+		Branch: "|-", LastBranch: "`-", Pipe: "| ",
+		Gutter:    "|",
+		SparkTrue: "T", SparkFalse: "F", SparkUnknown: "?",
+	}
+)
+
+// Theme controls how all of kubespy's human-readable output is styled.
+type Theme struct {
+	Color   ColorMode
+	Palette Palette
+	Glyphs  Glyphs
+}
+
+var theme = Theme{Color: ColorAuto, Palette: PaletteDark, Glyphs: EmojiGlyphs}
+
+// SetTheme styles all subsequent output with `t`.
+func SetTheme(t Theme) error {
+	switch t.Color {
+	case ColorAuto:
+		// `color` already disables itself if `NO_COLOR` is set or stdout is not a terminal.
+	case ColorAlways:
+		color.NoColor = false
+	case ColorNever:
+		color.NoColor = true
+	default:
+		return fmt.Errorf("Unknown color mode '%s'; must be one of: auto, always, never", t.Color)
+	}
+
+	switch t.Palette {
+	case PaletteDark:
+		setColors(color.FgYellow, color.FgCyan)
+	case PaletteLight:
+		setColors(color.FgMagenta, color.FgBlue)
+	default:
+		return fmt.Errorf("Unknown palette '%s'; must be one of: dark, light", t.Palette)
+	}
+
+	theme = t
+	return nil
+}
+
+// CurrentTheme returns the Theme output is styled with.
+func CurrentTheme() Theme {
+	return theme
+}
+
+// ColorEnabled reports whether output is colored.
+func ColorEnabled() bool {
+	return !color.NoColor
+}
+
+// setColors sets the colors used for warnings and for the names of objects (and where in them a diff
+// changes them), which are the ones that are illegible on some backgrounds.
+func setColors(warning, name color.Attribute) {
+	greenText = color.New(color.FgGreen)
+	faintGreenText = color.New(color.Faint, color.FgGreen)
+	yellowText = color.New(warning)
+	yellowBoldText = color.New(warning, color.Bold)
+	cyanText = color.New(name)
+	cyanBoldText = color.New(name, color.Bold)
+	redBoldText = color.New(color.FgRed, color.Bold)
+	blueBoldText = color.New(color.FgBlue, color.Bold)
+	whiteBoldText = color.New(color.Bold)
+	faintText = color.New(color.Faint)
+	diffColors = diff.Colors{
+		Added:    color.New(color.FgGreen),
+		Removed:  color.New(color.FgRed),
+		Location: color.New(name),
+		File:     color.New(color.Bold),
+	}
+}
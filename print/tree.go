@@ -0,0 +1,109 @@
+package print
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/kubespy/trace"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// cell is a single cell of a table, e.g., the ownership tree. Color is applied after padding, so
+// that escape codes do not throw off column alignment.
+type cell struct {
+	text  string
+	color *color.Color
+}
+
+// treeText prints an object and every one of its descendants as a tree.
+func treeText(w io.Writer, t *trace.Tree) {
+	if t.Root == nil {
+		cyanBoldText.Fprintf(w, "Waiting for %s '%s/%s'\n", t.ObjectKind, t.Namespace, t.Name)
+		return
+	}
+
+	rows := [][]cell{{
+		{"NAMESPACE", whiteBoldText}, {"NAME", whiteBoldText}, {"READY", whiteBoldText},
+		{"REASON", whiteBoldText}, {"AGE", whiteBoldText},
+	}}
+
+	var addNode func(node *trace.TreeNode, indent, branch string)
+	addNode = func(node *trace.TreeNode, indent, branch string) {
+		rows = append(rows, []cell{
+			{node.Namespace, nil},
+			{fmt.Sprintf("%s%s%s/%s", indent, branch, node.Kind, node.Name), cyanText},
+			readinessCell(node.Ready),
+			{node.Reason, nil},
+			{age(node.Created), nil},
+		})
+
+		childIndent := indent
+		switch branch {
+		case theme.Glyphs.LastBranch:
+			childIndent += strings.Repeat(" ", utf8.RuneCountInString(branch))
+		case theme.Glyphs.Branch:
+			childIndent += theme.Glyphs.Pipe
+		}
+		for i, child := range node.Children {
+			if i == len(node.Children)-1 {
+				addNode(child, childIndent, theme.Glyphs.LastBranch)
+			} else {
+				addNode(child, childIndent, theme.Glyphs.Branch)
+			}
+		}
+	}
+	addNode(t.Root, "", "")
+	writeTable(w, rows)
+	eventsText(w, t.Events)
+}
+
+// writeTable prints `rows` as a table, with columns padded to the width of their widest cell.
+func writeTable(w io.Writer, rows [][]cell) {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, c := range row {
+			if n := utf8.RuneCountInString(c.text); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, c := range row {
+			text := c.text
+			if i < len(row)-1 {
+				text += strings.Repeat(" ", widths[i]-utf8.RuneCountInString(text))
+			}
+			if c.color != nil {
+				text = c.color.Sprint(text)
+			}
+			cells[i] = text
+		}
+		fmt.Fprintln(w, strings.Join(cells, "  "))
+	}
+}
+
+func readinessCell(status string) cell {
+	switch status {
+	case trueStatus:
+		return cell{status, greenText}
+	case "False":
+		return cell{status, redBoldText}
+	case "":
+		return cell{"-", faintText}
+	default:
+		return cell{status, yellowText}
+	}
+}
+
+func age(created time.Time) string {
+	if created.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(created))
+}
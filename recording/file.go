@@ -0,0 +1,247 @@
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is how a recording file is compressed.
+type Compression string
+
+const (
+	// NoCompression writes plain NDJSON (or JSON, in the legacy format).
+	NoCompression Compression = "none"
+	// Gzip compresses with gzip.
+	Gzip Compression = "gzip"
+	// Zstd compresses with Zstandard, which is faster than gzip, and compresses better.
+	Zstd Compression = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ParseCompression parses the name of a Compression. If `name` is empty, the compression is chosen
+// by the extension of `path`: `.gz` for gzip, and `.zst` for zstd.
+func ParseCompression(name, path string) (Compression, error) {
+	switch Compression(name) {
+	case NoCompression, Gzip, Zstd:
+		return Compression(name), nil
+	case "":
+		switch filepath.Ext(path) {
+		case ".gz":
+			return Gzip, nil
+		case ".zst":
+			return Zstd, nil
+		default:
+			return NoCompression, nil
+		}
+	default:
+		return "", fmt.Errorf("Unknown compression '%s'; must be one of: none, gzip, zstd", name)
+	}
+}
+
+// FileOptions control how a recording is written to files.
+type FileOptions struct {
+	Compression Compression
+
+	// RotateSize, if nonzero, is how many bytes a file may grow to before the recording continues in
+	// a new one. RotateInterval, if nonzero, is how long the recording may continue in the same file.
+	RotateSize     int64
+	RotateInterval time.Duration
+}
+
+// NewFileWriter creates a Writer that writes a recording to the file `path`, compressing it as
+// `opts` specifies. Each event is flushed to the file as soon as it is written, so a recording cut
+// short is still readable.
+//
+// If the recording is rotated, it is written to a series of files named after `path`: for
+// `soak.ndjson.gz`, they are `soak.1.ndjson.gz`, `soak.2.ndjson.gz`, and so on. Each file is a
+// complete recording, created by `newWriter`, so (e.g.) each begins with a header, and a recording
+// of diffs records the first state of each object in each file in full.
+func NewFileWriter(
+	path string, opts FileOptions, newWriter func(w io.Writer) (Writer, error),
+) (Writer, error) {
+	fw := &fileWriter{path: path, opts: opts, newWriter: newWriter}
+	if err := fw.open(); err != nil {
+		return nil, err
+	}
+	return fw, nil
+}
+
+type fileWriter struct {
+	path      string
+	opts      FileOptions
+	newWriter func(w io.Writer) (Writer, error)
+
+	// segment is the number of the current file, counting from 1, opened is when it was opened, and
+	// events is how many events have been written to it.
+	segment int
+	opened  time.Time
+	events  int
+
+	file       *os.File
+	counter    *countingWriter
+	compressor compressor
+	writer     Writer
+}
+
+// compressor is a compressing writer that can flush everything written so far.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+func (fw *fileWriter) rotates() bool {
+	return fw.opts.RotateSize > 0 || fw.opts.RotateInterval > 0
+}
+
+// open starts the next file of the recording.
+func (fw *fileWriter) open() error {
+	fw.segment++
+	path := fw.path
+	if fw.rotates() {
+		path = SegmentPath(fw.path, fw.segment)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	fw.file, fw.opened, fw.events = file, time.Now(), 0
+	fw.counter = &countingWriter{w: file}
+
+	switch fw.opts.Compression {
+	case Gzip:
+		fw.compressor = gzip.NewWriter(fw.counter)
+	case Zstd:
+		if fw.compressor, err = zstd.NewWriter(fw.counter); err != nil {
+			return err
+		}
+	default:
+		fw.compressor = nopCompressor{fw.counter}
+	}
+
+	if fw.writer, err = fw.newWriter(fw.compressor); err != nil {
+		return err
+	}
+	return fw.compressor.Flush()
+}
+
+// close finishes the current file of the recording.
+func (fw *fileWriter) close() error {
+	if err := fw.writer.Close(); err != nil {
+		return err
+	}
+	if err := fw.compressor.Close(); err != nil {
+		return err
+	}
+	return fw.file.Close()
+}
+
+func (fw *fileWriter) Write(e Event) error {
+	// Every file has at least one event, however small RotateSize is.
+	if fw.events > 0 && ((fw.opts.RotateSize > 0 && fw.counter.n >= fw.opts.RotateSize) ||
+		(fw.opts.RotateInterval > 0 && time.Since(fw.opened) >= fw.opts.RotateInterval)) {
+		if err := fw.close(); err != nil {
+			return err
+		}
+		if err := fw.open(); err != nil {
+			return err
+		}
+	}
+
+	if err := fw.writer.Write(e); err != nil {
+		return err
+	}
+	fw.events++
+	return fw.compressor.Flush()
+}
+
+func (fw *fileWriter) Close() error {
+	return fw.close()
+}
+
+// SegmentPath is the path of file number `segment` of a rotated recording written to `path`. The
+// number goes before the extension of the recording's format, and that of its compression, if any,
+// so that `my.app.ndjson.gz` is followed by `my.app.1.ndjson.gz`.
+func SegmentPath(path string, segment int) string {
+	exts := ""
+	if ext := filepath.Ext(path); ext == ".gz" || ext == ".zst" {
+		path, exts = strings.TrimSuffix(path, ext), ext
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.%d%s", strings.TrimSuffix(path, ext), segment, ext+exts)
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+type nopCompressor struct {
+	io.Writer
+}
+
+func (nopCompressor) Flush() error { return nil }
+func (nopCompressor) Close() error { return nil }
+
+// decompress returns a reader of the decompressed contents of `r`, if it is compressed with gzip or
+// zstd, and of `r` itself otherwise.
+func decompress(r *bufio.Reader) (*bufio.Reader, error) {
+	// Recordings shorter than the magic numbers can't be compressed.
+	magic, _ := r.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return bufio.NewReader(gz), nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return bufio.NewReader(zr), nil
+	default:
+		return r, nil
+	}
+}
+
+// ReadFiles reads the recordings in the files `paths` as one recording, in order, e.g., the files of
+// a rotated recording. Its header is that of the first file. Every event is held in memory; see
+// OpenFiles to read them one at a time.
+func ReadFiles(paths []string) (*Recording, error) {
+	s, err := OpenFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	rec := &Recording{Header: s.Header(), Events: []Event{}}
+	for s.Scan() {
+		rec.Events = append(rec.Events, s.Event())
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
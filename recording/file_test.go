@@ -0,0 +1,191 @@
+package recording
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newFileWriter(t *testing.T, path string, opts FileOptions, diffs bool) Writer {
+	t.Helper()
+	w, err := NewFileWriter(path, opts, func(w io.Writer) (Writer, error) {
+		return NewWriter(w, Header{Diffs: diffs})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestCompressedRoundTrip(t *testing.T) {
+	tests := []struct {
+		compression Compression
+		magic       []byte
+	}{
+		{NoCompression, []byte(`{"`)},
+		{Gzip, gzipMagic},
+		{Zstd, zstdMagic},
+	}
+	for _, test := range tests {
+		for _, diffs := range []bool{false, true} {
+			path := filepath.Join(t.TempDir(), "rec.ndjson")
+			w := newFileWriter(t, path, FileOptions{Compression: test.compression}, diffs)
+			writeAll(t, w, sampleEvents())
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(raw, test.magic) {
+				t.Errorf("%s: expected the file to begin with %q, got %q",
+					test.compression, test.magic, raw[:len(test.magic)])
+			}
+
+			rec, err := ReadFiles([]string{path})
+			if err != nil {
+				t.Fatalf("%s, diffs=%t: %v", test.compression, diffs, err)
+			}
+			assertEvents(t, rec.Events, sampleEvents())
+		}
+	}
+}
+
+func TestCompressedCutShort(t *testing.T) {
+	for _, compression := range []Compression{Gzip, Zstd} {
+		path := filepath.Join(t.TempDir(), "rec.ndjson")
+		w := newFileWriter(t, path, FileOptions{Compression: compression}, true)
+		for _, e := range sampleEvents() {
+			if err := w.Write(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// The writer is never closed, as if kubespy crashed, so the compressed stream is never
+		// finished. Every event written so far was flushed, though.
+		rec, err := ReadFiles([]string{path})
+		if err != nil {
+			t.Fatalf("%s: %v", compression, err)
+		}
+		assertEvents(t, rec.Events, sampleEvents())
+	}
+}
+
+func TestRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "my.app.ndjson.gz")
+	// Every event overflows the file it's written to.
+	w := newFileWriter(t, path, FileOptions{Compression: Gzip, RotateSize: 1}, true)
+	writeAll(t, w, sampleEvents())
+
+	paths := []string{}
+	for i := 1; i <= len(sampleEvents()); i++ {
+		paths = append(paths, filepath.Join(dir, fmt.Sprintf("my.app.%d.ndjson.gz", i)))
+		if got := SegmentPath(path, i); got != paths[i-1] {
+			t.Errorf("Expected segment %d at %s, got %s", i, paths[i-1], got)
+		}
+	}
+	if _, err := os.Stat(SegmentPath(path, len(paths)+1)); !os.IsNotExist(err) {
+		t.Errorf("Expected %d segments, but found another", len(paths))
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected no file at %s when rotating", path)
+	}
+
+	// Each segment is a complete recording, even of diffs.
+	for i, segment := range paths {
+		rec, err := ReadFiles([]string{segment})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.Header == nil || !rec.Header.Diffs {
+			t.Errorf("Segment %d: expected a header, got %+v", i+1, rec.Header)
+		}
+		assertEvents(t, rec.Events, sampleEvents()[i:i+1])
+	}
+
+	rec, err := ReadFiles(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEvents(t, rec.Events, sampleEvents())
+}
+
+func TestSegmentPath(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"soak.ndjson", "soak.3.ndjson"},
+		{"soak.ndjson.gz", "soak.3.ndjson.gz"},
+		{"soak.ndjson.zst", "soak.3.ndjson.zst"},
+		{"my.app.ndjson", "my.app.3.ndjson"},
+		{"my.app.ndjson.zst", "my.app.3.ndjson.zst"},
+		{"soak", "soak.3"},
+		{"soak.gz", "soak.3.gz"},
+		{filepath.Join("runs.d", "soak"), filepath.Join("runs.d", "soak.3")},
+	}
+	for _, test := range tests {
+		if got := SegmentPath(test.path, 3); got != test.want {
+			t.Errorf("SegmentPath(%q, 3) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestIndexFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rec.ndjson")
+	w := newFileWriter(t, path, FileOptions{RotateSize: 1}, true)
+	writeAll(t, w, sampleEvents())
+
+	paths := []string{}
+	for i := 1; i <= len(sampleEvents()); i++ {
+		paths = append(paths, SegmentPath(path, i))
+	}
+	index, err := IndexFiles(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(index.Times) != len(sampleEvents()) {
+		t.Fatalf("Expected %d times, got %v", len(sampleEvents()), index.Times)
+	}
+	for i, e := range sampleEvents() {
+		if !index.Times[i].Equal(e.Time) {
+			t.Errorf("Event %d: expected time %s, got %s", i, e.Time, index.Times[i])
+		}
+	}
+	if index.Objects != 2 {
+		t.Errorf("Expected 2 objects, got %d", index.Objects)
+	}
+	if _, _, _, name, single := index.Subject(); name != "nginx-u1" || single {
+		t.Errorf("Expected the object recorded first, of several, got %s (single=%t)", name, single)
+	}
+}
+
+func TestScannerReportsFile(t *testing.T) {
+	dir := t.TempDir()
+	good, bad := filepath.Join(dir, "good.ndjson"), filepath.Join(dir, "bad.ndjson")
+	writeAll(t, newFileWriter(t, good, FileOptions{}, false), sampleEvents())
+	if err := os.WriteFile(bad, []byte("not a recording\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := OpenFiles([]string{good, bad})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	n := 0
+	for s.Scan() {
+		n++
+	}
+	if n != len(sampleEvents()) {
+		t.Errorf("Expected the %d events of the first file, got %d", len(sampleEvents()), n)
+	}
+	if err := s.Err(); err == nil || !strings.Contains(err.Error(), bad) {
+		t.Errorf("Expected an error naming %s, got %v", bad, err)
+	}
+}
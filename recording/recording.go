@@ -0,0 +1,289 @@
+// Package recording reads the sessions written by `kubespy record`, so that they can be inspected
+// after the fact by people without access to the cluster.
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pulumi/kubespy/diff"
+	"github.com/pulumi/pulumi-kubernetes/provider/v4/pkg/openapi"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8sWatch "k8s.io/apimachinery/pkg/watch"
+)
+
+// Event is a single change to an object, as recorded.
+type Event struct {
+	// Time is when the change was made, or the zero time if it is not known.
+	Time   time.Time
+	Type   k8sWatch.EventType
+	Object *unstructured.Unstructured
+}
+
+// Recording is a recorded session.
+type Recording struct {
+	// Header is nil if the recording is in the legacy format.
+	Header *Header
+	Events []Event
+}
+
+// Subject is the object the recording is of: the one object recorded, if the header says only one
+// was. Otherwise (e.g., every Deployment in a namespace was recorded, or the recording is in the
+// legacy format, which has no header) `single` is false, and the object recorded first is returned.
+func (r *Recording) Subject() (apiVersion, kind, namespace, name string, single bool) {
+	var first *unstructured.Unstructured
+	if len(r.Events) > 0 {
+		first = r.Events[0].Object
+	}
+	return subject(r.Header, first)
+}
+
+// EventsRegardSubject is true if every Kubernetes Event recorded regards the one object recorded,
+// or an object it owns (directly or not), since `record --events` records only those.
+func (r *Recording) EventsRegardSubject() bool {
+	_, _, _, _, single := r.Subject()
+	return single && r.Header.Filters.Events
+}
+
+// subject is the Subject of a recording with `header`, which recorded `first` first.
+func subject(header *Header, first *unstructured.Unstructured) (
+	apiVersion, kind, namespace, name string, single bool,
+) {
+	if header != nil {
+		if apiVersion, kind, namespace, name, ok := header.Filters.Subject(); ok {
+			return apiVersion, kind, namespace, name, true
+		}
+	}
+	if first == nil {
+		return "", "", "", "", false
+	}
+	return first.GetAPIVersion(), first.GetKind(), first.GetNamespace(), first.GetName(), false
+}
+
+// Read reads every event in the recording `r`. See Open.
+func Read(r io.Reader) ([]Event, error) {
+	rec, err := Open(r)
+	if err != nil {
+		return nil, err
+	}
+	return rec.Events, nil
+}
+
+// Open reads the recording `r`, in either format `kubespy record` writes: a header followed by one
+// event per line (see NewWriter), or the legacy JSON array of object states (see NewLegacyWriter).
+// Recordings compressed with gzip or zstd are decompressed. Every event is held in memory; see
+// NewScanner to read them one at a time.
+func Open(r io.Reader) (*Recording, error) {
+	s, err := NewScanner(r)
+	if err != nil {
+		return nil, err
+	}
+	rec := &Recording{Header: s.Header(), Events: []Event{}}
+	for s.Scan() {
+		rec.Events = append(rec.Events, s.Event())
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// open begins reading the recording `r`. It returns the recording's header (nil in the legacy
+// format), and a function that reads each event in turn, and returns io.EOF after the last.
+func open(r io.Reader) (header *Header, next func() (Event, error), err error) {
+	br, err := decompress(bufio.NewReader(r))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to read recording: %v", err)
+	}
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Unable to read recording: %v", err)
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			if _, err := br.ReadByte(); err != nil {
+				return nil, nil, err
+			}
+			continue
+		case '[':
+			next, err := readLegacy(br)
+			return nil, next, err
+		default:
+			return readNDJSON(br)
+		}
+	}
+}
+
+// readNDJSON reads a header, followed by one event per line. A truncated last line (e.g., because
+// the recording was cut short) is ignored.
+func readNDJSON(r *bufio.Reader) (*Header, func() (Event, error), error) {
+	line := 0
+	// readLine returns the next line that isn't blank, and whether it is the last, or io.EOF.
+	readLine := func() (text []byte, last bool, err error) {
+		for {
+			line++
+			text, err := r.ReadBytes('\n')
+			// A compressed recording that was cut short ends unexpectedly.
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				return nil, false, fmt.Errorf("Unable to read recording: %v", err)
+			}
+			last := err != nil
+			if len(bytes.TrimSpace(text)) > 0 {
+				return text, last, nil
+			} else if last {
+				return nil, false, io.EOF
+			}
+		}
+	}
+
+	text, last, err := readLine()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("Unable to read recording: it is empty")
+	} else if err != nil {
+		return nil, nil, err
+	}
+	header := &Header{}
+	if jsonErr := json.Unmarshal(text, header); jsonErr != nil || header.Format != headerFormat {
+		return nil, nil, fmt.Errorf("Unable to read recording: not a kubespy recording")
+	} else if header.Version > FormatVersion {
+		return nil, nil, fmt.Errorf(
+			"Unable to read recording: format version %d is newer than this kubespy supports (%d)",
+			header.Version, FormatVersion)
+	}
+
+	// Only recordings of diffs need the last state of each object, to apply patches to.
+	var states map[types.UID]map[string]interface{}
+	if header.Diffs {
+		states = map[types.UID]map[string]interface{}{}
+	}
+	done := last
+	next := func() (Event, error) {
+		if done {
+			return Event{}, io.EOF
+		}
+		text, last, err := readLine()
+		if err != nil {
+			return Event{}, err
+		}
+		done = last
+
+		e, jsonErr := readEnvelope(text, states)
+		if jsonErr != nil {
+			if last {
+				return Event{}, io.EOF
+			}
+			return Event{}, fmt.Errorf("Unable to read line %d of recording: %v", line, jsonErr)
+		}
+		return e, nil
+	}
+	return header, next, nil
+}
+
+// readEnvelope reads a single event. `states` are the last states of the objects read so far, which
+// patches are applied to, or nil if the recording has no patches.
+func readEnvelope(line []byte, states map[types.UID]map[string]interface{}) (Event, error) {
+	var env envelope
+	if err := json.Unmarshal(line, &env); err != nil {
+		return Event{}, err
+	}
+
+	object := []byte(env.Object)
+	if env.UID != "" {
+		last, exists := states[env.UID]
+		if !exists {
+			return Event{}, fmt.Errorf("Patch of object %s, which was never recorded in full", env.UID)
+		}
+		patched, err := diff.Apply(runtime.DeepCopyJSON(last), env.Patch)
+		if err != nil {
+			return Event{}, err
+		}
+		if object, err = json.Marshal(patched); err != nil {
+			return Event{}, err
+		}
+	}
+
+	// Decode as the dynamic client does, e.g., so that integers are `int64`s, not `float64`s.
+	o := &unstructured.Unstructured{}
+	if err := o.UnmarshalJSON(object); err != nil {
+		return Event{}, err
+	}
+	if states != nil && env.Type == k8sWatch.Deleted {
+		delete(states, o.GetUID())
+	} else if states != nil {
+		states[o.GetUID()] = o.Object
+	}
+	return Event{Time: env.Time, Type: env.Type, Object: o}, nil
+}
+
+// readLegacy reads a JSON array of the successive states of each recorded object, one at a time.
+// These carry no event metadata, so the first state of each object is reported as `ADDED` and the
+// rest as `MODIFIED`, and the time of each change is taken from the object's
+// `.metadata.managedFields`.
+func readLegacy(r io.Reader) (func() (Event, error), error) {
+	dec := json.NewDecoder(r)
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("Unable to read recording: %v", err)
+	}
+
+	seen := map[types.UID]bool{}
+	i := 0
+	next := func() (Event, error) {
+		if !dec.More() {
+			if _, err := dec.Token(); err != nil {
+				return Event{}, fmt.Errorf("Unable to read recording: %v", err)
+			}
+			return Event{}, io.EOF
+		}
+		var object json.RawMessage
+		if err := dec.Decode(&object); err != nil {
+			return Event{}, fmt.Errorf("Unable to read recording: %v", err)
+		}
+
+		// Decode as the dynamic client does, e.g., so that integers are `int64`s, not `float64`s.
+		o := &unstructured.Unstructured{}
+		if err := o.UnmarshalJSON(object); err != nil {
+			return Event{}, fmt.Errorf("Unable to read object %d of recording: %v", i, err)
+		}
+		i++
+
+		e := Event{Time: lastManaged(o), Type: k8sWatch.Modified, Object: o}
+		if !seen[o.GetUID()] {
+			e.Type = k8sWatch.Added
+			seen[o.GetUID()] = true
+		}
+		return e, nil
+	}
+	return next, nil
+}
+
+// lastManaged returns the time of the most recent write to `o` by any field manager, or the zero
+// time if there is none.
+func lastManaged(o *unstructured.Unstructured) time.Time {
+	var last time.Time
+
+	managedFieldsI, _ := openapi.Pluck(o.Object, "metadata", "managedFields")
+	managedFields, _ := managedFieldsI.([]interface{})
+	for _, entryI := range managedFields {
+		entry, isMap := entryI.(map[string]interface{})
+		if !isMap {
+			continue
+		}
+		timeS, _ := entry["time"].(string)
+		t, err := time.Parse(time.RFC3339, timeS)
+		if err == nil && t.After(last) {
+			last = t
+		}
+	}
+
+	if last.IsZero() {
+		return o.GetCreationTimestamp().Time
+	}
+	return last
+}
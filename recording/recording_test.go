@@ -0,0 +1,202 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sWatch "k8s.io/apimachinery/pkg/watch"
+)
+
+var start = time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+func deployment(uid, image string, replicas int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name": "nginx-" + uid, "namespace": "default", "uid": uid,
+		},
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+			"template": map[string]interface{}{"spec": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "nginx", "image": image},
+				},
+			}},
+		},
+	}}
+}
+
+// sampleEvents creates, changes, and deletes a couple of objects.
+func sampleEvents() []Event {
+	return []Event{
+		{start, k8sWatch.Added, deployment("u1", "nginx:1.14", 1)},
+		{start.Add(time.Second), k8sWatch.Added, deployment("u2", "redis:7", 1)},
+		{start.Add(2 * time.Second), k8sWatch.Modified, deployment("u1", "nginx:1.15", 1)},
+		{start.Add(3 * time.Second), k8sWatch.Modified, deployment("u1", "nginx:1.15", 3)},
+		{start.Add(4 * time.Second), k8sWatch.Deleted, deployment("u2", "redis:7", 1)},
+		// An object whose UID is reused after its deletion is recorded in full again.
+		{start.Add(5 * time.Second), k8sWatch.Added, deployment("u2", "redis:8", 2)},
+	}
+}
+
+func writeAll(t *testing.T, w Writer, events []Event) {
+	t.Helper()
+	for _, e := range events {
+		if err := w.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertEvents(t *testing.T, got, want []Event) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d events, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if !got[i].Time.Equal(want[i].Time) || got[i].Type != want[i].Type ||
+			!reflect.DeepEqual(got[i].Object.Object, want[i].Object.Object) {
+			t.Errorf("Event %d: got %s %s %v, want %s %s %v", i, got[i].Time, got[i].Type,
+				got[i].Object.Object, want[i].Time, want[i].Type, want[i].Object.Object)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, diffs := range []bool{false, true} {
+		var b bytes.Buffer
+		header := Header{Filters: Filters{Kinds: []string{"apps/v1/Deployment"}}, Diffs: diffs}
+		w, err := NewWriter(&b, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeAll(t, w, sampleEvents())
+
+		// With diffs, only the changes to `u1` are written as patches.
+		lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+		patches := 0
+		for _, line := range lines[1:] {
+			var env envelope
+			if err := json.Unmarshal([]byte(line), &env); err != nil {
+				t.Fatal(err)
+			}
+			if env.Patch != nil {
+				patches++
+			}
+		}
+		if wantPatches := map[bool]int{false: 0, true: 2}[diffs]; patches != wantPatches {
+			t.Errorf("diffs=%t: expected %d patches, got %d", diffs, wantPatches, patches)
+		}
+
+		rec, err := Open(&b)
+		if err != nil {
+			t.Fatalf("diffs=%t: %v", diffs, err)
+		}
+		if rec.Header == nil || rec.Header.Diffs != diffs ||
+			!reflect.DeepEqual(rec.Header.Filters, header.Filters) {
+			t.Errorf("diffs=%t: unexpected header %+v", diffs, rec.Header)
+		}
+		assertEvents(t, rec.Events, sampleEvents())
+	}
+}
+
+func TestTruncatedTail(t *testing.T) {
+	for _, diffs := range []bool{false, true} {
+		var b bytes.Buffer
+		w, err := NewWriter(&b, Header{Diffs: diffs})
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeAll(t, w, sampleEvents())
+
+		// Cut the recording off in the middle of its last line, as a crash might.
+		text := strings.TrimSuffix(b.String(), "\n")
+		truncated := text[:strings.LastIndex(text, "\n")+20]
+
+		rec, err := Open(strings.NewReader(truncated))
+		if err != nil {
+			t.Fatalf("diffs=%t: %v", diffs, err)
+		}
+		want := sampleEvents()
+		assertEvents(t, rec.Events, want[:len(want)-1])
+	}
+}
+
+func TestCorruptLine(t *testing.T) {
+	var b bytes.Buffer
+	w, err := NewWriter(&b, Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeAll(t, w, sampleEvents()[:1])
+	b.WriteString("{not json\n")
+	writeAll(t, w, sampleEvents()[1:2])
+
+	// Only the last line may be cut short.
+	if _, err := Open(&b); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Expected an error reading line 3, got %v", err)
+	}
+}
+
+func TestLegacy(t *testing.T) {
+	var b bytes.Buffer
+	writeAll(t, NewLegacyWriter(&b), sampleEvents())
+
+	rec, err := Open(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Header != nil {
+		t.Errorf("Expected no header, got %+v", rec.Header)
+	}
+
+	// The legacy format records neither deletions nor times, and every object's first state is
+	// read as its creation.
+	want := []Event{}
+	for _, e := range sampleEvents() {
+		if e.Type != k8sWatch.Deleted {
+			want = append(want, Event{Type: e.Type, Object: e.Object})
+		}
+	}
+	want[len(want)-1].Type = k8sWatch.Modified
+	assertEvents(t, rec.Events, want)
+}
+
+func TestLegacyTimes(t *testing.T) {
+	legacy := `[
+  {"apiVersion": "v1", "kind": "ConfigMap",
+   "metadata": {"name": "c", "uid": "u1", "managedFields": [
+     {"manager": "kubectl", "time": "2024-01-02T15:04:05Z"},
+     {"manager": "controller", "time": "2024-01-02T15:05:05Z"}]}}
+]`
+	rec, err := Open(strings.NewReader(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Events) != 1 || !rec.Events[0].Time.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected the time of the last write, got %+v", rec.Events)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	for _, text := range []string{"", "\n\n"} {
+		if _, err := Open(strings.NewReader(text)); err == nil {
+			t.Errorf("Expected an error reading %q", text)
+		}
+	}
+
+	var b bytes.Buffer
+	writeAll(t, NewLegacyWriter(&b), nil)
+	if rec, err := Open(&b); err != nil || len(rec.Events) != 0 {
+		t.Errorf("Expected an empty legacy recording, got %v, %v", rec, err)
+	}
+}
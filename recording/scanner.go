@@ -0,0 +1,174 @@
+package recording
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Scanner reads a recording one event at a time, as bufio.Scanner reads lines, so that a long
+// recording can be played back without holding every state of every object in memory. Only the
+// latest state of each object is kept, and only if the recording stores diffs, which are applied to
+// it.
+type Scanner struct {
+	header *Header
+	event  Event
+	err    error
+
+	// next reads the next event of the file being read, if any. `paths` are the files still to be
+	// read after it, and `file` is the one being read, whose path is `path`.
+	next  func() (Event, error)
+	paths []string
+	file  *os.File
+	path  string
+}
+
+// NewScanner begins reading the recording `r`, in either format Open reads.
+func NewScanner(r io.Reader) (*Scanner, error) {
+	header, next, err := open(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Scanner{header: header, next: next}, nil
+}
+
+// OpenFiles begins reading the recordings in the files `paths` as one recording, in order, as
+// ReadFiles does. Its header is that of the first file. The Scanner must be closed, to close the
+// file being read.
+func OpenFiles(paths []string) (*Scanner, error) {
+	s := &Scanner{}
+	if len(paths) == 0 {
+		return s, nil
+	}
+	s.paths = paths[1:]
+	if err := s.openFile(paths[0]); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Scanner) openFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	header, next, err := open(f)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if s.header == nil {
+		s.header = header
+	}
+	s.next, s.file, s.path = next, f, path
+	return nil
+}
+
+// Header is the header of the recording, or nil if it is in the legacy format.
+func (s *Scanner) Header() *Header {
+	return s.header
+}
+
+// Scan advances to the next event, which is then available through Event. It returns false once
+// there are no more events, or an error has occurred, which Err then returns.
+func (s *Scanner) Scan() bool {
+	for s.err == nil && s.next != nil {
+		e, err := s.next()
+		if err == nil {
+			s.event = e
+			return true
+		} else if err != io.EOF {
+			s.err = err
+			if s.file != nil {
+				s.err = fmt.Errorf("%s: %v", s.path, err)
+			}
+			break
+		}
+
+		s.next = nil
+		s.Close()
+		if len(s.paths) > 0 {
+			path := s.paths[0]
+			s.paths = s.paths[1:]
+			s.err = s.openFile(path)
+		}
+	}
+	return false
+}
+
+// Event is the event the last call to Scan advanced to.
+func (s *Scanner) Event() Event {
+	return s.event
+}
+
+// Err is the first error that occurred while scanning, if any.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Close closes the file being read, if any.
+func (s *Scanner) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// Index describes a recording without holding its objects in memory, e.g., so that a position in it
+// can be found before it is played back.
+type Index struct {
+	// Header is nil if the recording is in the legacy format.
+	Header *Header
+
+	// Times are when each event happened, in order. A time is zero if it is not known.
+	Times []time.Time
+
+	// Objects is how many different objects were recorded.
+	Objects int
+
+	// first is the object recorded first.
+	first *unstructured.Unstructured
+}
+
+// IndexFiles reads the recordings in the files `paths` as OpenFiles does, and indexes them.
+func IndexFiles(paths []string) (*Index, error) {
+	s, err := OpenFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	index := &Index{Header: s.Header(), Times: []time.Time{}}
+	uids := map[types.UID]bool{}
+	for s.Scan() {
+		e := s.Event()
+		if index.first == nil {
+			index.first = e.Object
+		}
+		index.Times = append(index.Times, e.Time)
+		uids[e.Object.GetUID()] = true
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	index.Objects = len(uids)
+	return index, nil
+}
+
+// Subject is the object the recording is of, as Recording.Subject describes.
+func (x *Index) Subject() (apiVersion, kind, namespace, name string, single bool) {
+	return subject(x.Header, x.first)
+}
+
+// EventsRegardSubject is true if every Kubernetes Event recorded regards the one object recorded,
+// as Recording.EventsRegardSubject describes.
+func (x *Index) EventsRegardSubject() bool {
+	_, _, _, _, single := x.Subject()
+	return single && x.Header.Filters.Events
+}
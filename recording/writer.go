@@ -0,0 +1,212 @@
+package recording
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pulumi/kubespy/diff"
+	"k8s.io/apimachinery/pkg/types"
+	k8sWatch "k8s.io/apimachinery/pkg/watch"
+)
+
+// FormatVersion is the newest version of the recording format, which Read understands. It is
+// incremented whenever a change to the format would confuse older readers. Version 2 added
+// recordings that store diffs (see Header.Diffs); others are still written as version 1.
+const FormatVersion = 2
+
+// Header is the first line of a recording. It describes how the recording was made.
+type Header struct {
+	// Format is always `kubespy-recording`, and Version is the FormatVersion it was written with.
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+
+	// KubespyVersion is the version of kubespy that made the recording.
+	KubespyVersion string    `json:"kubespyVersion"`
+	Time           time.Time `json:"time"`
+
+	// Context and Cluster are the kubeconfig context and cluster that were recorded, if known.
+	Context string `json:"context,omitempty"`
+	Cluster string `json:"cluster,omitempty"`
+
+	// Command is the command line that made the recording, and Filters describe which objects it
+	// recorded.
+	Command []string `json:"command,omitempty"`
+	Filters Filters  `json:"filters"`
+
+	// Diffs is true if only the first state of each object is recorded in full, and each later one
+	// as a JSON Patch of the one before.
+	Diffs bool `json:"diffs,omitempty"`
+}
+
+// Filters describe which objects a recording contains.
+type Filters struct {
+	// Kinds are the kinds of objects recorded, each as `<apiVersion>/<kind>`, e.g.,
+	// `apps/v1/Deployment`.
+	Kinds []string `json:"kinds"`
+
+	// Namespace is empty if objects in every namespace were recorded.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the one object recorded, if only one was. Otherwise, every object of
+	// Kinds was recorded, or every one matching Selector.
+	Name     string `json:"name,omitempty"`
+	Selector string `json:"selector,omitempty"`
+
+	// Events is true if the Kubernetes Events regarding the objects recorded, and every object they
+	// own, were recorded too.
+	Events bool `json:"events,omitempty"`
+}
+
+// Subject returns the one object recorded, if only one was.
+func (f Filters) Subject() (apiVersion, kind, namespace, name string, ok bool) {
+	if f.Name == "" || len(f.Kinds) != 1 {
+		return "", "", "", "", false
+	}
+	i := strings.LastIndex(f.Kinds[0], "/")
+	if i == -1 {
+		return "", "", "", "", false
+	}
+	return f.Kinds[0][:i], f.Kinds[0][i+1:], f.Namespace, f.Name, true
+}
+
+const headerFormat = "kubespy-recording"
+
+// GVK is the group, version, and kind of a recorded object.
+type GVK struct {
+	Group   string `json:"group,omitempty"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// envelope is every line of a recording after the header: a single watch event. It has either the
+// object, or, in a recording of diffs, the UID of an object recorded earlier and a patch of it.
+type envelope struct {
+	Time   time.Time          `json:"time"`
+	Type   k8sWatch.EventType `json:"type"`
+	GVK    GVK                `json:"gvk"`
+	Object json.RawMessage    `json:"object,omitempty"`
+
+	UID   types.UID        `json:"uid,omitempty"`
+	Patch []diff.Operation `json:"patch,omitempty"`
+}
+
+// Writer writes a recording.
+type Writer interface {
+	// Write records a single event.
+	Write(e Event) error
+
+	// Close finishes the recording. It does not close the underlying writer.
+	Close() error
+}
+
+// NewWriter creates a Writer that writes `header`, followed by each event as a JSON envelope of its
+// time, type, GVK, and object, one per line (i.e., NDJSON). Each event is written as soon as it
+// is recorded, so a recording cut short (e.g., by a crash) is still readable. If `header.Diffs` is
+// set, only the first state of each object is written in full.
+func NewWriter(w io.Writer, header Header) (Writer, error) {
+	header.Format, header.Version = headerFormat, 1
+	if header.Diffs {
+		header.Version = 2
+	}
+	if err := writeLine(w, header); err != nil {
+		return nil, err
+	}
+
+	nw := &ndjsonWriter{w: w}
+	if header.Diffs {
+		nw.last = map[types.UID]map[string]interface{}{}
+	}
+	return nw, nil
+}
+
+type ndjsonWriter struct {
+	w io.Writer
+
+	// last is the last state written of each object, if only diffs of later states are written.
+	last map[types.UID]map[string]interface{}
+}
+
+func (nw *ndjsonWriter) Write(e Event) error {
+	gvk := e.Object.GroupVersionKind()
+	env := envelope{
+		Time: e.Time.UTC(),
+		Type: e.Type,
+		GVK:  GVK{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind},
+	}
+
+	if nw.last != nil {
+		uid := e.Object.GetUID()
+		last, exists := nw.last[uid]
+		if e.Type == k8sWatch.Deleted {
+			delete(nw.last, uid)
+		} else {
+			nw.last[uid] = e.Object.Object
+		}
+		if exists && e.Type != k8sWatch.Added {
+			env.UID, env.Patch = uid, diff.Patch(last, e.Object.Object)
+			return writeLine(nw.w, env)
+		}
+	}
+
+	object, err := e.Object.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	env.Object = object
+	return writeLine(nw.w, env)
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nil
+}
+
+func writeLine(w io.Writer, v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// NewLegacyWriter creates a Writer that writes the original recording format: a JSON array of the
+// successive states of each object. Event types and times are not recorded, and neither are
+// deletions. The array is terminated only once the Writer is closed.
+func NewLegacyWriter(w io.Writer) Writer {
+	return &legacyWriter{w: w}
+}
+
+type legacyWriter struct {
+	w       io.Writer
+	started bool
+}
+
+func (lw *legacyWriter) Write(e Event) error {
+	if e.Type == k8sWatch.Deleted {
+		return nil
+	}
+
+	object, err := json.MarshalIndent(e.Object.Object, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	separator := "[\n  "
+	if lw.started {
+		separator = ",\n  "
+	}
+	lw.started = true
+	_, err = fmt.Fprint(lw.w, separator+string(object))
+	return err
+}
+
+func (lw *legacyWriter) Close() error {
+	if !lw.started {
+		_, err := fmt.Fprintln(lw.w, "[]")
+		return err
+	}
+	_, err := fmt.Fprintln(lw.w, "\n]")
+	return err
+}
@@ -0,0 +1,203 @@
+// Package replay plays sessions recorded with `kubespy record` back, at the pace they were recorded,
+// faster, or one event at a time, so that they can be watched with the same views as live ones.
+package replay
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pulumi/kubespy/recording"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	k8sWatch "k8s.io/apimachinery/pkg/watch"
+)
+
+// Options control how a recording is played back.
+type Options struct {
+	// Speed is how many times faster than real time to play the recording. If it is 0, events are
+	// played as fast as possible.
+	Speed float64
+
+	// From is the index of the first event to show. Earlier events are observed, so that the views
+	// have the right state, but not shown.
+	From int
+
+	// Step, if set, is read for a line before each event is shown, e.g., so that the user can press
+	// enter to advance to the next one.
+	Step io.Reader
+}
+
+// Play reads the events of `events` and feeds them to `observe` in order, waiting between them as
+// `opts` specifies. `show` is false for the events before `opts.From`. `observe` is given the index
+// of each event.
+func Play(
+	events *recording.Scanner, opts Options, observe func(i int, e recording.Event, show bool),
+) error {
+	var step *bufio.Reader
+	if opts.Step != nil {
+		step = bufio.NewReader(opts.Step)
+	}
+
+	var last time.Time
+	for i := 0; events.Scan(); i++ {
+		e := events.Event()
+		show := i >= opts.From
+		if show {
+			switch {
+			case step != nil:
+				if _, err := step.ReadString('\n'); err != nil && err != io.EOF {
+					return err
+				}
+			case opts.Speed > 0 && i > opts.From:
+				time.Sleep(Delay(last, e.Time, opts.Speed))
+			}
+		}
+		if !e.Time.IsZero() {
+			last = e.Time
+		}
+		observe(i, e, show)
+	}
+	return events.Err()
+}
+
+// Delay is how long to wait between events recorded at `from` and `to`, played back at `speed`
+// times real time. Events whose times are unknown (or out of order) are played back immediately.
+func Delay(from, to time.Time, speed float64) time.Duration {
+	if from.IsZero() || to.IsZero() || !to.After(from) || speed <= 0 {
+		return 0
+	}
+	return time.Duration(float64(to.Sub(from)) / speed)
+}
+
+// Offsets is when each of the events that happened at `times` is played at `speed` times real time,
+// counting from the first. Events whose times are unknown (e.g., in recordings in the legacy
+// format) are played `unknown` after the one before.
+func Offsets(times []time.Time, speed float64, unknown time.Duration) []time.Duration {
+	offsets := make([]time.Duration, len(times))
+	var last time.Time
+	for i, t := range times {
+		if i > 0 {
+			offsets[i] = offsets[i-1] + Delay(last, t, speed)
+			if t.IsZero() {
+				offsets[i] = offsets[i-1] + unknown
+			}
+		}
+		if !t.IsZero() {
+			last = t
+		}
+	}
+	return offsets
+}
+
+// ParseSpeed parses a playback speed, e.g., `1x` (real time), `10x`, `0.5`, or `max` (as fast as
+// possible, which is speed 0).
+func ParseSpeed(s string) (float64, error) {
+	if s == "max" {
+		return 0, nil
+	}
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(s, "x"), 64)
+	if err != nil || speed <= 0 {
+		return 0, fmt.Errorf("Invalid speed '%s'; must be, e.g., '1x', '10x', '0.5x', or 'max'", s)
+	}
+	return speed, nil
+}
+
+// Seek finds the index of the first of the events that happened at `times` that is at or after
+// `to`, which is either a timestamp (in RFC 3339 format, e.g., `2024-01-02T15:04:05Z`), an offset
+// from the first event (e.g., `90s`), or the number of an event, counting from 1 (e.g., `#12`).
+func Seek(times []time.Time, to string) (int, error) {
+	if to == "" {
+		return 0, nil
+	}
+
+	n, at, err := parsePosition(times, to)
+	if err != nil {
+		return 0, err
+	} else if at.IsZero() {
+		return n - 1, nil
+	}
+
+	for i, t := range times {
+		if !t.Before(at) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("No events at or after %s", at.Format(time.RFC3339))
+}
+
+// Upto finds how many of the events that happened at `times` had happened as of `at`, which is a
+// position as Seek accepts: for a time, the number of events up to and including it, and for an
+// event number, that number.
+func Upto(times []time.Time, at string) (int, error) {
+	n, t, err := parsePosition(times, at)
+	if err != nil || t.IsZero() {
+		return n, err
+	}
+
+	n = 0
+	for _, eventTime := range times {
+		if eventTime.After(t) {
+			break
+		}
+		n++
+	}
+	return n, nil
+}
+
+// parsePosition parses a position among the events that happened at `times`, as Seek accepts. It
+// returns either the number of an event, counting from 1, or a time.
+func parsePosition(times []time.Time, position string) (n int, at time.Time, err error) {
+	if strings.HasPrefix(position, "#") {
+		n, err := strconv.Atoi(position[1:])
+		if err != nil || n < 1 || n > len(times) {
+			return 0, time.Time{}, fmt.Errorf(
+				"Invalid event number '%s'; the recording has events #1 to #%d", position, len(times))
+		}
+		return n, time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, position); err == nil {
+		return 0, t, nil
+	} else if offset, err := time.ParseDuration(position); err == nil {
+		return 0, start(times).Add(offset), nil
+	}
+	return 0, time.Time{}, fmt.Errorf(
+		"Invalid position '%s'; must be a timestamp (e.g., '2024-01-02T15:04:05Z'), an offset "+
+			"from the start (e.g., '90s'), or an event number (e.g., '#12')", position)
+}
+
+// States reads the next `n` events of `events` into `states`, the state of every object keyed by
+// UID, so that it is their state after those events. Objects that are deleted are removed.
+func States(
+	events *recording.Scanner, n int, states map[types.UID]*unstructured.Unstructured,
+) error {
+	for i := 0; i < n; i++ {
+		if !events.Scan() {
+			if err := events.Err(); err != nil {
+				return err
+			}
+			return fmt.Errorf("Recording ended after %d more events; expected %d", i, n)
+		}
+		e := events.Event()
+		if e.Type == k8sWatch.Deleted {
+			delete(states, e.Object.GetUID())
+		} else {
+			states[e.Object.GetUID()] = e.Object
+		}
+	}
+	return nil
+}
+
+// start is the first of `times` that is known.
+func start(times []time.Time) time.Time {
+	for _, t := range times {
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
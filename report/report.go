@@ -0,0 +1,226 @@
+// Package report renders a recorded session as a self-contained HTML page, so that it can be shared
+// with people who have no access to the cluster.
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pulumi/kubespy/print"
+	"github.com/pulumi/kubespy/recording"
+	"github.com/pulumi/kubespy/trace"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	k8sWatch "k8s.io/apimachinery/pkg/watch"
+)
+
+// step is a single event of the recording, along with the state of the trace after it.
+type step struct {
+	Index     int
+	Time      time.Time
+	EventType k8sWatch.EventType
+	Kind      string
+	Namespace string
+	Name      string
+
+	ResourceVersion string
+	Diff            []diffLine
+	Verdict         trace.Verdict
+
+	// Trace is empty if the event didn't change the trace as rendered.
+	Trace string
+}
+
+type diffLine struct {
+	Class string
+	Text  string
+}
+
+type page struct {
+	Title     string
+	Generated time.Time
+	Subject   trace.Object
+	Steps     []step
+}
+
+// Write renders the HTML report of `rec`, titled `title`, to `w`. The recording's subject (see
+// recording.Recording.Subject) is the subject of the report: after each event, the report shows the
+// state of its trace. If several objects were recorded, that is the one recorded first.
+//
+// Traces are rendered with the current print.Theme, so colors should be disabled beforehand.
+func Write(w io.Writer, title string, rec *recording.Recording) error {
+	events, filtered := rec.Events, rec.EventsRegardSubject()
+	p := page{Title: title, Generated: time.Now().UTC(), Steps: []step{}}
+	if len(events) > 0 {
+		apiVersion, kind, namespace, name, _ := rec.Subject()
+		p.Subject = trace.Object{
+			APIVersion: apiVersion, Kind: kind, Namespace: namespace, Name: name,
+		}
+	}
+
+	snapshots := trace.NewSnapshotter(p.Subject.APIVersion, p.Subject.Kind, p.Subject.Namespace,
+		p.Subject.Name, filtered)
+	latest := map[types.UID]k8sWatch.Event{}
+	lastTrace := ""
+	for i, e := range events {
+		o := e.Object
+		s := step{
+			Index:           i + 1,
+			Time:            e.Time,
+			EventType:       e.Type,
+			Kind:            o.GetKind(),
+			Namespace:       o.GetNamespace(),
+			Name:            o.GetName(),
+			ResourceVersion: o.GetResourceVersion(),
+		}
+
+		var err error
+		prev, hasPrev := latest[o.GetUID()]
+		if s.Diff, err = diff(prev, hasPrev, e); err != nil {
+			return err
+		}
+
+		latest[o.GetUID()] = k8sWatch.Event{Type: e.Type, Object: o}
+		snapshots.Observe(latest[o.GetUID()])
+
+		m := snapshots.Model()
+		var text bytes.Buffer
+		print.WriteText(&text, m)
+		s.Verdict = m.Verdict()
+		if text.String() != lastTrace {
+			s.Trace, lastTrace = text.String(), text.String()
+		}
+
+		p.Steps = append(p.Steps, s)
+	}
+
+	return pageTemplate.Execute(w, p)
+}
+
+// Summarize summarizes the rollout recorded in `rec`, whose subject (see
+// recording.Recording.Subject) must be a Deployment. Recordings of several objects are rejected,
+// since it is not clear whose rollout they are of. Recordings in the legacy format don't say, so
+// they are assumed to be of the object recorded first.
+func Summarize(rec *recording.Recording) (*trace.RolloutSummary, error) {
+	events, filtered := rec.Events, rec.EventsRegardSubject()
+	if len(events) == 0 {
+		return nil, fmt.Errorf("Recording is empty")
+	}
+	apiVersion, kind, namespace, name, single := rec.Subject()
+	if !single && rec.Header != nil {
+		return nil, fmt.Errorf("Summaries are only supported for recordings of a single " +
+			"Deployment, not of several objects")
+	} else if kind != "Deployment" {
+		return nil, fmt.Errorf("Summaries are only supported for recordings of Deployments, not %ss",
+			kind)
+	}
+
+	s := trace.NewRolloutSummary(namespace, name)
+	snapshots := trace.NewSnapshotter(apiVersion, kind, namespace, name, filtered)
+	for _, e := range events {
+		snapshots.Observe(k8sWatch.Event{Type: e.Type, Object: e.Object})
+		s.Observe(e.Time, snapshots.Model().(*trace.Deployment))
+	}
+	return s, nil
+}
+
+// diff computes the lines of the diff between an object's previous state, if any, and its state
+// after `e`. An object's first state is shown in full.
+func diff(prev k8sWatch.Event, hasPrev bool, e recording.Event) ([]diffLine, error) {
+	if !hasPrev || prev.Type == k8sWatch.Deleted {
+		j, err := json.MarshalIndent(e.Object.Object, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		lines := []diffLine{}
+		for _, line := range strings.Split(string(j), "\n") {
+			lines = append(lines, diffLine{Class: "added", Text: line})
+		}
+		return lines, nil
+	}
+
+	before := prev.Object.(*unstructured.Unstructured)
+	text, modified, err := print.PlainObjectDiff(before.Object, e.Object.Object)
+	if err != nil {
+		return nil, err
+	} else if !modified {
+		return []diffLine{}, nil
+	}
+
+	lines := []diffLine{}
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		l := diffLine{Text: line}
+		switch {
+		case strings.HasPrefix(line, "+"):
+			l.Class = "added"
+		case strings.HasPrefix(line, "-"):
+			l.Class = "removed"
+		}
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+var pageTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"formatTime": func(t time.Time) string {
+		if t.IsZero() {
+			return "unknown time"
+		}
+		return t.UTC().Format(time.RFC3339)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} - kubespy report</title>
+<style>
+  body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 72em; padding: 0 1em; color: #24292f; }
+  h1 { font-size: 1.5em; }
+  pre { background: #f6f8fa; padding: 0.75em; overflow-x: auto; font-size: 0.85em; line-height: 1.4; }
+  .meta { color: #57606a; }
+  .step { border-left: 3px solid #d0d7de; margin: 1.5em 0; padding-left: 1em; }
+  .step h2 { font-size: 1em; margin: 0 0 0.5em; }
+  .type { font-family: monospace; font-weight: bold; }
+  .ADDED { color: #1a7f37; } .MODIFIED { color: #0969da; } .DELETED { color: #cf222e; }
+  .verdict { font-family: monospace; padding: 0 0.4em; border-radius: 0.3em; background: #eaeef2; }
+  .verdict.succeeded { background: #dafbe1; } .verdict.failed { background: #ffebe9; }
+  .diff span { display: block; min-height: 1.4em; }
+  .added { color: #1a7f37; background: #e6ffec; }
+  .removed { color: #cf222e; background: #ffebe9; }
+  summary { cursor: pointer; margin: 0.25em 0; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">
+  {{with .Subject}}{{if .Kind}}Recording of {{.Kind}} <code>{{if .Namespace}}{{.Namespace}}/{{end}}{{.Name}}</code> ({{.APIVersion}}), {{end}}{{end}}
+  {{len .Steps}} events. Generated by kubespy at {{formatTime .Generated}}.
+</p>
+{{range .Steps}}
+<div class="step" id="step-{{.Index}}">
+  <h2>
+    <a href="#step-{{.Index}}">#{{.Index}}</a>
+    <span class="meta">{{formatTime .Time}}</span>
+    <span class="type {{.EventType}}">{{.EventType}}</span>
+    {{.Kind}} <code>{{if .Namespace}}{{.Namespace}}/{{end}}{{.Name}}</code>
+    <span class="meta">resourceVersion {{.ResourceVersion}}</span>
+  </h2>
+  <details>
+    <summary>Changes</summary>
+    <pre class="diff">{{range .Diff}}<span class="{{.Class}}">{{.Text}}</span>{{end}}</pre>
+  </details>
+  {{if .Trace}}<details>
+    <summary>Trace <span class="verdict {{.Verdict}}">{{.Verdict}}</span></summary>
+    <pre>{{.Trace}}</pre>
+  </details>{{else}}<p class="meta">Trace unchanged
+    <span class="verdict {{.Verdict}}">{{.Verdict}}</span></p>{{end}}
+</div>
+{{end}}
+</body>
+</html>
+`))
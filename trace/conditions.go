@@ -0,0 +1,178 @@
+package trace
+
+import (
+	"time"
+
+	"github.com/pulumi/kubespy/k8sobject"
+	"github.com/pulumi/pulumi-kubernetes/provider/v4/pkg/openapi"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sWatch "k8s.io/apimachinery/pkg/watch"
+)
+
+// maxTransitions is the number of transitions remembered for each condition.
+const maxTransitions = 40
+
+// Conditions is the state of an object's `.status.conditions`, and the transitions each condition
+// has made since the trace began. Conditions are read generically, so that any kind that follows
+// the API conventions (`type`, `status`, `reason`, `message`, and `lastTransitionTime`) can be
+// traced.
+type Conditions struct {
+	APIVersion string `json:"apiVersion"`
+	ObjectKind string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+
+	// Object is nil until the object has been observed.
+	Object *Object `json:"object,omitempty"`
+
+	// Generation is the object's `.metadata.generation`, and ObservedGeneration its
+	// `.status.observedGeneration`, or nil if the object doesn't report one.
+	Generation         int64  `json:"generation"`
+	ObservedGeneration *int64 `json:"observedGeneration,omitempty"`
+
+	// Ready is the status of the object's `Ready` (or `Available`) condition, or empty if readiness
+	// can't be determined. See `k8sobject.Readiness`.
+	Ready string `json:"ready,omitempty"`
+
+	// Conditions are in the order they were first observed.
+	Conditions []*Condition `json:"conditions"`
+}
+
+// Condition is a single status condition, e.g., a Deployment's `Available` condition.
+type Condition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	// LastTransitionTime is zero if the object doesn't report it.
+	LastTransitionTime time.Time `json:"lastTransitionTime,omitempty"`
+
+	// Transitions are the statuses the condition has had, oldest first, including the current one.
+	// Once a condition is removed from the object, its status is empty.
+	Transitions []Transition `json:"transitions"`
+}
+
+// Transition is a change in the status of a condition.
+type Transition struct {
+	Time   time.Time `json:"time"`
+	Status string    `json:"status"`
+}
+
+// Kind implements Model.
+func (c *Conditions) Kind() string { return "Conditions" }
+
+// Verdict implements Model. An object has succeeded once it is ready, and its controller has
+// observed its latest generation. Once it is deleted, the trace is waiting for it to be recreated.
+func (c *Conditions) Verdict() Verdict {
+	if c.Object == nil || c.Object.EventType == k8sWatch.Deleted {
+		return Waiting
+	} else if (c.Ready == trueStatus || c.Ready == "") && c.GenerationLag() == 0 {
+		return Succeeded
+	}
+	return Progressing
+}
+
+// GenerationLag is the number of generations of the object that its controller has yet to observe,
+// or 0 if the object doesn't report its observed generation.
+func (c *Conditions) GenerationLag() int64 {
+	if c.ObservedGeneration == nil || *c.ObservedGeneration >= c.Generation {
+		return 0
+	}
+	return c.Generation - *c.ObservedGeneration
+}
+
+// NewConditions creates a trace of the conditions of `apiVersion` `kind` `namespace/name`, which has
+// yet to be observed.
+func NewConditions(apiVersion, kind, namespace, name string) *Conditions {
+	return &Conditions{
+		APIVersion: apiVersion, ObjectKind: kind, Namespace: namespace, Name: name,
+		Conditions: []*Condition{},
+	}
+}
+
+// Observe updates the trace with a watch event of the object, at time `now`.
+func (c *Conditions) Observe(e k8sWatch.Event, now time.Time) {
+	o := e.Object.(*unstructured.Unstructured)
+	c.Object = objectFromEvent(e)
+	if e.Type == k8sWatch.Deleted {
+		// The deleted object's last state is not its current one: it has none, and so no conditions.
+		c.Ready = ""
+		c.removeConditions(map[string]bool{}, now)
+		return
+	}
+	c.Generation = o.GetGeneration()
+	c.ObservedGeneration = nil
+	if observedI, exists := openapi.Pluck(o.Object, "status", "observedGeneration"); exists {
+		if observed, isInt := observedI.(int64); isInt {
+			c.ObservedGeneration = &observed
+		}
+	}
+	c.Ready, _ = k8sobject.Readiness(o)
+
+	seen := map[string]bool{}
+	conditionsI, _ := openapi.Pluck(o.Object, "status", "conditions")
+	conditions, _ := conditionsI.([]interface{})
+	for _, conditionI := range conditions {
+		condition, isMap := conditionI.(map[string]interface{})
+		if !isMap {
+			continue
+		}
+		conditionType, _ := condition["type"].(string)
+		if conditionType == "" || seen[conditionType] {
+			continue
+		}
+		seen[conditionType] = true
+
+		cond := c.condition(conditionType)
+		cond.Status, _ = condition["status"].(string)
+		cond.Reason, _ = condition["reason"].(string)
+		cond.Message, _ = condition["message"].(string)
+		cond.LastTransitionTime = time.Time{}
+		if transitionI, isString := condition["lastTransitionTime"].(string); isString {
+			cond.LastTransitionTime, _ = time.Parse(time.RFC3339, transitionI)
+		}
+
+		at := now
+		if !cond.LastTransitionTime.IsZero() {
+			at = cond.LastTransitionTime
+		}
+		cond.transition(at, cond.Status)
+	}
+
+	c.removeConditions(seen, now)
+}
+
+// removeConditions records that every condition but those `seen` was removed as of `now`, e.g.,
+// because the object's controller removed it, or the object was deleted.
+func (c *Conditions) removeConditions(seen map[string]bool, now time.Time) {
+	for _, cond := range c.Conditions {
+		if !seen[cond.Type] {
+			cond.Status, cond.Reason, cond.Message = "", "", ""
+			cond.transition(now, "")
+		}
+	}
+}
+
+// condition returns the condition of type `conditionType`, adding it if it is new.
+func (c *Conditions) condition(conditionType string) *Condition {
+	for _, cond := range c.Conditions {
+		if cond.Type == conditionType {
+			return cond
+		}
+	}
+	cond := &Condition{Type: conditionType, Transitions: []Transition{}}
+	c.Conditions = append(c.Conditions, cond)
+	return cond
+}
+
+// transition records that the condition has `status` as of `at`, if that is a change.
+func (cond *Condition) transition(at time.Time, status string) {
+	if n := len(cond.Transitions); n > 0 && cond.Transitions[n-1].Status == status {
+		return
+	}
+	cond.Transitions = append(cond.Transitions, Transition{Time: at, Status: status})
+	if len(cond.Transitions) > maxTransitions {
+		cond.Transitions = cond.Transitions[len(cond.Transitions)-maxTransitions:]
+	}
+}
@@ -0,0 +1,76 @@
+package trace
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sWatch "k8s.io/apimachinery/pkg/watch"
+)
+
+// readyDeployment is the Deployment `default/nginx`, whose `Available` condition is `True`.
+func readyDeployment() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name": "nginx", "namespace": "default", "generation": int64(1),
+		},
+		"status": map[string]interface{}{
+			"observedGeneration": int64(1),
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Available", "status": "True"},
+			},
+		},
+	}}
+}
+
+func TestConditionsVerdict(t *testing.T) {
+	tests := []struct {
+		name       string
+		events     []k8sWatch.EventType
+		want       Verdict
+		wantStatus string
+	}{
+		{name: "not yet observed", want: Waiting},
+		{
+			name:       "ready",
+			events:     []k8sWatch.EventType{k8sWatch.Added},
+			want:       Succeeded,
+			wantStatus: "True",
+		},
+		{
+			// The deleted object carries its last state, which must not count as ready.
+			name:       "deleted",
+			events:     []k8sWatch.EventType{k8sWatch.Added, k8sWatch.Deleted},
+			want:       Waiting,
+			wantStatus: "",
+		},
+		{
+			name:       "recreated",
+			events:     []k8sWatch.EventType{k8sWatch.Added, k8sWatch.Deleted, k8sWatch.Added},
+			want:       Succeeded,
+			wantStatus: "True",
+		},
+	}
+	for _, test := range tests {
+		c := NewConditions("apps/v1", "Deployment", "default", "nginx")
+		now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		for _, eventType := range test.events {
+			now = now.Add(time.Second)
+			c.Observe(k8sWatch.Event{Type: eventType, Object: readyDeployment()}, now)
+		}
+
+		if got := c.Verdict(); got != test.want {
+			t.Errorf("%s: Verdict() = %s, want %s", test.name, got, test.want)
+		}
+		if len(test.events) == 0 {
+			continue
+		}
+		if len(c.Conditions) != 1 {
+			t.Errorf("%s: %d conditions, want 1", test.name, len(c.Conditions))
+		} else if got := c.Conditions[0].Status; got != test.wantStatus {
+			t.Errorf("%s: Available is '%s', want '%s'", test.name, got, test.wantStatus)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/pulumi/kubespy/k8sobject"
+	"github.com/pulumi/kubespy/pods"
+	"github.com/pulumi/pulumi-kubernetes/provider/v4/pkg/openapi"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sWatch "k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	deploymentRevisionKey = "deployment.kubernetes.io/revision"
+
+	statusAvailable = "Available"
+)
+
+// Deployment is the state of a rollout of a Deployment, and of the ReplicaSets and Pods it manages.
+type Deployment struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// Deployment is nil until the Deployment has been observed.
+	Deployment *Object `json:"deployment,omitempty"`
+	Revision   int     `json:"revision"`
+
+	// SpecReplicas is the number of Pods the Deployment should have, of which AvailableReplicas are
+	// available.
+	SpecReplicas      int64 `json:"specReplicas"`
+	AvailableReplicas int64 `json:"availableReplicas"`
+
+	// RollingOut is true once the Deployment controller has begun to roll out `Revision`.
+	RollingOut bool    `json:"rollingOut"`
+	Checks     []Check `json:"checks"`
+
+	// Current is the ReplicaSet of the revision being rolled out, and Previous is the ReplicaSet
+	// being scaled down, if any.
+	Current  *ReplicaSet `json:"current,omitempty"`
+	Previous *ReplicaSet `json:"previous,omitempty"`
+
+	// Events are the most recent Kubernetes Events regarding the Deployment and the objects it
+	// owns, if they are being watched.
+	Events []*Event `json:"events,omitempty"`
+
+	verdict Verdict
+}
+
+// Kind implements Model.
+func (d *Deployment) Kind() string { return "Deployment" }
+
+// Verdict implements Model. A Deployment has succeeded once the controller reports the new
+// ReplicaSet is available and no old ReplicaSet has Pods left, and has failed once the controller
+// stops rolling forward (e.g., the progress deadline was exceeded).
+func (d *Deployment) Verdict() Verdict {
+	return d.verdict
+}
+
+// ReplicaSet is the state of one of a Deployment's ReplicaSets, and of the Pods it owns.
+type ReplicaSet struct {
+	Object
+	Revision          int     `json:"revision"`
+	Replicas          int64   `json:"replicas"`
+	SpecReplicas      int64   `json:"specReplicas"`
+	AvailableReplicas int64   `json:"availableReplicas"`
+	Checks            []Check `json:"checks"`
+	Pods              []Pod   `json:"pods"`
+}
+
+// Pod is the health of a Pod, along with the log tails of its failing containers, if requested.
+type Pod struct {
+	pods.Health
+	Logs map[string]pods.LogTail `json:"logs,omitempty"`
+}
+
+// NewDeployment computes the state of the Deployment `namespace/name` from `table`, which maps
+// `apiVersion/Kind` to the latest watch events observed for objects of that type. If `kubeEvents`
+// is non-nil, the Kubernetes Events in it regarding the Deployment's Pods are the evidence of which
+// of their containers are failing their probes. If `logs` is non-nil, the log tails of failing
+// containers are taken from it (as fetched so far).
+func NewDeployment(
+	namespace, name string, table map[string][]k8sWatch.Event, kubeEvents *EventLog,
+	logs *pods.LogTailer,
+) *Deployment {
+	const (
+		waitingForControllerCreate = "Waiting for controller to create Deployment"
+		rolloutNotStarted          = "Deployment has not begun to roll out the change"
+		appUnavailable             = "Deployment does not have minimum replicas (%d out of %d)"
+	)
+
+	d := &Deployment{Namespace: namespace, Name: name, Checks: []Check{}, verdict: Waiting}
+
+	if events, hasDepl := table[DeploymentKey]; hasDepl {
+		o := events[0].Object.(*unstructured.Unstructured)
+		var err error
+		d.Revision, err = parseRevision(o)
+		if err != nil {
+			d.Checks = append(d.Checks, check(Failure, waitingForControllerCreate))
+		}
+	}
+
+	// Get current ReplicaSet.
+	var currRepSet, prevRepSet *unstructured.Unstructured
+	var currRepSetEventType, prevRepSetEventType k8sWatch.EventType
+	if events, hasRs := table[ReplicaSetKey]; hasRs {
+		for _, e := range events {
+			rs := e.Object.(*unstructured.Unstructured)
+			repSetRevision, err := parseRevision(rs)
+			if err != nil {
+				continue
+			}
+			if d.Revision == repSetRevision {
+				currRepSet = rs
+				currRepSetEventType = e.Type
+			} else if e.Type != k8sWatch.Deleted {
+				replicasI, _ := openapi.Pluck(rs.Object, "status", "replicas")
+				replicas, _ := replicasI.(int64)
+				if replicas > 0 {
+					prevRepSet = rs
+					prevRepSetEventType = e.Type
+				}
+			}
+		}
+	}
+
+	// Compute `Deployment` status.
+	if events, hasDepl := table[DeploymentKey]; hasDepl {
+		o := events[0].Object.(*unstructured.Unstructured)
+		d.Deployment = objectFromEvent(events[0])
+		d.verdict = Progressing
+
+		specReplicasI, _ := openapi.Pluck(o.Object, "spec", "replicas")
+		specReplicas, isInt := specReplicasI.(int64)
+		if !isInt {
+			specReplicas = 1
+		}
+
+		availableReplicasI, _ := openapi.Pluck(o.Object, "status", "availableReplicas")
+		availableReplicas, _ := availableReplicasI.(int64)
+		d.SpecReplicas, d.AvailableReplicas = specReplicas, availableReplicas
+
+		// Until the controller has observed the latest change to the Deployment, its status (and
+		// conditions) describe the rollout of the one before.
+		generationI, _ := openapi.Pluck(o.Object, "metadata", "generation")
+		generation, _ := generationI.(int64)
+		observedGenerationI, _ := openapi.Pluck(o.Object, "status", "observedGeneration")
+		observedGeneration, _ := observedGenerationI.(int64)
+
+		pausedI, _ := openapi.Pluck(o.Object, "spec", "paused")
+		paused, _ := pausedI.(bool)
+
+		// Check Deployments conditions to see whether new ReplicaSet is available. If it is, we are
+		// successful.
+		conditionsI, _ := openapi.Pluck(o.Object, "status", "conditions")
+		conditions, isSlice := conditionsI.([]interface{})
+		if !isSlice {
+			d.Checks = append(d.Checks,
+				check(Failure, appUnavailable, 0, specReplicas),
+				check(Failure, rolloutNotStarted))
+		} else {
+			d.RollingOut = true
+
+			var hasProgressing, deadlineExceeded bool
+			var progressingReason string
+
+			var deploymentAvailable bool
+			var availableReason string
+
+			var rolloutSuccessful bool
+
+			// Success occurs when the ReplicaSet of the `currentGeneration` is marked as available, and
+			// when the deployment is available.
+			for _, rawCondition := range conditions {
+				condition, isMap := rawCondition.(map[string]interface{})
+				if !isMap {
+					continue
+				}
+
+				reason, hasReason := condition["reason"].(string)
+				message, hasMessage := condition["message"].(string)
+
+				if condition["type"] == "Progressing" {
+					hasProgressing = true
+					// A paused rollout's Progressing condition is `Unknown`.
+					paused = paused || condition["status"] == "Unknown"
+					deadlineExceeded = reason == "ProgressDeadlineExceeded"
+					rolloutSuccessful = reason == "NewReplicaSetAvailable"
+					if !hasReason || !hasMessage {
+						continue
+					}
+					progressingReason = fmt.Sprintf("[%s] %s", reason, message)
+				}
+
+				if condition["type"] == statusAvailable {
+					deploymentAvailable = condition["status"] == trueStatus
+					if !hasReason || !hasMessage {
+						continue
+					}
+					availableReason = fmt.Sprintf("[%s] %s", reason, message)
+				}
+			}
+
+			if !deploymentAvailable {
+				d.Checks = append(d.Checks,
+					check(Failure, "Deployment is failing; %d out of %d Pods are available: %s",
+						availableReplicas, specReplicas, availableReason))
+			} else {
+				d.Checks = append(d.Checks, check(Success, "Deployment is currently available"))
+			}
+
+			// Without a Progressing condition (e.g., when `progressDeadlineSeconds` is unset on an
+			// old cluster), the rollout is judged as `kubectl rollout status` does: by whether every
+			// replica is updated and available, and none of the old ones remain.
+			if !hasProgressing {
+				updatedReplicasI, _ := openapi.Pluck(o.Object, "status", "updatedReplicas")
+				updatedReplicas, _ := updatedReplicasI.(int64)
+				replicasI, _ := openapi.Pluck(o.Object, "status", "replicas")
+				replicas, _ := replicasI.(int64)
+				rolloutSuccessful = updatedReplicas == specReplicas && replicas == updatedReplicas &&
+					availableReplicas == updatedReplicas
+				progressingReason = fmt.Sprintf("%d out of %d new replicas have been updated",
+					updatedReplicas, specReplicas)
+			}
+
+			switch {
+			case observedGeneration < generation:
+				d.Checks = append(d.Checks, check(Pending,
+					"Waiting for controller to observe the change to the Deployment "+
+						"(generation %d; observed %d)", generation, observedGeneration))
+			case deadlineExceeded:
+				d.verdict = Failed
+				d.Checks = append(d.Checks,
+					check(Failure, "Rollout has failed; controller is no longer rolling forward: %s",
+						progressingReason))
+			case paused:
+				d.Checks = append(d.Checks, check(Pending, "Rollout is paused: %s", progressingReason))
+			case rolloutSuccessful:
+				if deploymentAvailable {
+					d.verdict = Succeeded
+				}
+				d.Checks = append(d.Checks,
+					check(Success, "Rollout successful: new ReplicaSet marked 'available'"))
+			default:
+				d.Checks = append(d.Checks, check(Pending, "Rollout proceeding: %s", progressingReason))
+			}
+		}
+	}
+
+	// Compute `ReplicaSet` status.
+	if currRepSet != nil {
+		d.Current = newReplicaSet(currRepSetEventType, currRepSet, table, kubeEvents, logs)
+		if d.Current.AvailableReplicas < d.Current.SpecReplicas {
+			d.Current.Checks = append(d.Current.Checks, check(Pending,
+				"Waiting for ReplicaSet to attain minimum available Pods (%d available of a %d minimum)",
+				d.Current.AvailableReplicas, d.Current.SpecReplicas))
+		} else {
+			d.Current.Checks = append(d.Current.Checks, check(Success,
+				"ReplicaSet is available [%d Pods available of a %d minimum]",
+				d.Current.AvailableReplicas, d.Current.SpecReplicas))
+		}
+	}
+
+	if prevRepSet != nil {
+		if d.verdict == Succeeded {
+			d.verdict = Progressing
+		}
+		d.Previous = newReplicaSet(prevRepSetEventType, prevRepSet, table, kubeEvents, logs)
+		d.Previous.Checks = append(d.Previous.Checks, check(Pending,
+			"Waiting for ReplicaSet to scale to 0 Pods (%d currently exist)", d.Previous.Replicas))
+	}
+
+	if d.Deployment != nil && d.Deployment.EventType == k8sWatch.Deleted {
+		d.verdict = Waiting
+	}
+
+	return d
+}
+
+func newReplicaSet(
+	eventType k8sWatch.EventType, o *unstructured.Unstructured, table map[string][]k8sWatch.Event,
+	kubeEvents *EventLog, logs *pods.LogTailer,
+) *ReplicaSet {
+	rs := &ReplicaSet{
+		Object: *objectFromEvent(k8sWatch.Event{Type: eventType, Object: o}),
+		Checks: []Check{},
+		Pods:   []Pod{},
+	}
+	rs.Revision, _ = parseRevision(o)
+
+	replicasI, _ := openapi.Pluck(o.Object, "status", "replicas")
+	rs.Replicas, _ = replicasI.(int64)
+
+	specReplicasI, _ := openapi.Pluck(o.Object, "spec", "replicas")
+	specReplicas, isInt := specReplicasI.(int64)
+	if !isInt {
+		specReplicas = 1
+	}
+	rs.SpecReplicas = specReplicas
+
+	availableReplicasI, _ := openapi.Pluck(o.Object, "status", "availableReplicas")
+	rs.AvailableReplicas, _ = availableReplicasI.(int64)
+
+	for _, e := range table[PodKey] {
+		pod := e.Object.(*unstructured.Unstructured)
+		if !k8sobject.OwnedBy(pod, o.GetAPIVersion(), o.GetKind(), o.GetName()) {
+			continue
+		}
+
+		p := Pod{Health: pods.GetHealth(pod)}
+		if kubeEvents != nil {
+			for _, e := range kubeEvents.Regarding(pod) {
+				p.ObserveEvent(e.Reason, e.FieldPath, e.Message)
+			}
+		}
+		if logs != nil {
+			for _, c := range p.Containers {
+				// Containers that are waiting and have never run (e.g., `ImagePullBackOff`) have no
+				// logs.
+				if !c.Failing() || (c.State == "Waiting" && c.RestartCount == 0) {
+					continue
+				}
+				if p.Logs == nil {
+					p.Logs = map[string]pods.LogTail{}
+				}
+				p.Logs[c.Name] = logs.Tail(p.Health, c)
+			}
+		}
+		rs.Pods = append(rs.Pods, p)
+	}
+	sort.Slice(rs.Pods, func(i, j int) bool { return rs.Pods[i].Name < rs.Pods[j].Name })
+
+	return rs
+}
+
+func parseRevision(o *unstructured.Unstructured) (int, error) {
+	revisionI, _ := openapi.Pluck(o.Object, "metadata", "annotations", deploymentRevisionKey)
+	revisionS, _ := revisionI.(string)
+	return strconv.Atoi(revisionS)
+}
@@ -0,0 +1,328 @@
+package trace
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pulumi/pulumi-kubernetes/provider/v4/pkg/openapi"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// MaxEvents is the number of the most recent Events a trace shows.
+const MaxEvents = 10
+
+// maxLoggedEvents is how many Events an EventLog holds before it forgets those that happened least
+// recently.
+const maxLoggedEvents = 1000
+
+// forgetAfter is how long a Family remembers a member after it is deleted, so that Events about it
+// are still recognized. Kubernetes keeps Events for an hour by default.
+const forgetAfter = time.Hour
+
+// Event is something that happened to an object, e.g., a Pod failing to be scheduled, as reported
+// by a Kubernetes Event, folded together with every repetition of it. Events of both the `core/v1`
+// API (which name the object they regard `involvedObject`) and the `events.k8s.io/v1` API (which
+// name it `regarding`) are read.
+type Event struct {
+	// Type is `Normal` or `Warning`.
+	Type    string `json:"type"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+
+	// Regarding is the object the Event is about, and Source the component that reported it, e.g.,
+	// `default-scheduler`. FieldPath is the part of the object it is about, if any, e.g.,
+	// `spec.containers{app}` for one of a Pod's containers.
+	Regarding    Object    `json:"regarding"`
+	RegardingUID types.UID `json:"regardingUid,omitempty"`
+	FieldPath    string    `json:"fieldPath,omitempty"`
+	Source       string    `json:"source,omitempty"`
+
+	// Count is how many times it has happened, FirstTime when it first did, and LastTime when it
+	// most recently did. The times are zero if the Event doesn't report them.
+	Count     int64     `json:"count"`
+	FirstTime time.Time `json:"firstTime,omitempty"`
+	LastTime  time.Time `json:"lastTime,omitempty"`
+
+	// counts is the count of each Event object folded into this one.
+	counts map[types.UID]int64
+}
+
+// IsEvent reports whether `o` is a Kubernetes Event of either API.
+func IsEvent(o *unstructured.Unstructured) bool {
+	gvk := o.GroupVersionKind()
+	return gvk.Kind == "Event" && (gvk.Group == "" || gvk.Group == "events.k8s.io")
+}
+
+// newEvent reads the Event object `o`. It returns the Event, with none of its repetitions folded
+// in, and how many times the object says it has happened.
+func newEvent(o *unstructured.Unstructured) (*Event, int64) {
+	e := &Event{counts: map[types.UID]int64{}}
+	e.Type, _ = o.Object["type"].(string)
+	e.Reason, _ = o.Object["reason"].(string)
+
+	regardingI, isCore := o.Object["involvedObject"]
+	if isCore {
+		e.Message, _ = o.Object["message"].(string)
+		sourceI, _ := openapi.Pluck(o.Object, "source", "component")
+		e.Source, _ = sourceI.(string)
+	} else {
+		regardingI = o.Object["regarding"]
+		e.Message, _ = o.Object["note"].(string)
+		e.Source, _ = o.Object["reportingController"].(string)
+	}
+	if regarding, isMap := regardingI.(map[string]interface{}); isMap {
+		e.Regarding.APIVersion, _ = regarding["apiVersion"].(string)
+		e.Regarding.Kind, _ = regarding["kind"].(string)
+		e.Regarding.Namespace, _ = regarding["namespace"].(string)
+		e.Regarding.Name, _ = regarding["name"].(string)
+		e.FieldPath, _ = regarding["fieldPath"].(string)
+		uid, _ := regarding["uid"].(string)
+		e.RegardingUID = types.UID(uid)
+	}
+
+	// `core/v1` Events count their repetitions in `count`, and `events.k8s.io/v1` ones in
+	// `series`, which they have only once they have repeated. Either may carry the other's fields,
+	// prefixed with `deprecated`, if it was created through the other API.
+	count := int64(1)
+	for _, path := range [][]string{
+		{"count"}, {"deprecatedCount"}, {"series", "count"},
+	} {
+		if countI, exists := openapi.Pluck(o.Object, path...); exists {
+			if n, isInt := countI.(int64); isInt && n > count {
+				count = n
+			}
+		}
+	}
+
+	e.FirstTime = eventTime(o, []string{"firstTimestamp"}, []string{"deprecatedFirstTimestamp"},
+		[]string{"eventTime"}, []string{"metadata", "creationTimestamp"})
+	e.LastTime = eventTime(o, []string{"series", "lastObservedTime"}, []string{"lastTimestamp"},
+		[]string{"deprecatedLastTimestamp"}, []string{"eventTime"}, []string{"firstTimestamp"},
+		[]string{"metadata", "creationTimestamp"})
+	return e, count
+}
+
+// eventTime is the first of the times at `paths` in the Event object `o` that is set.
+func eventTime(o *unstructured.Unstructured, paths ...[]string) time.Time {
+	for _, path := range paths {
+		if timeI, exists := openapi.Pluck(o.Object, path...); exists {
+			if s, isString := timeI.(string); isString {
+				// `eventTime` has microseconds, and the others only seconds.
+				if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+					return t
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// key identifies the Events that are repetitions of each other: those that regard the same part of
+// the same object, and say the same thing.
+func (e *Event) key() [6]string {
+	regarding := string(e.RegardingUID)
+	if regarding == "" {
+		regarding = e.Regarding.Kind + "/" + e.Regarding.Namespace + "/" + e.Regarding.Name
+	}
+	return [6]string{regarding, e.FieldPath, e.Type, e.Reason, e.Message, e.Source}
+}
+
+// Regards reports whether the Event is about the object `kind` `namespace/name` with UID `uid`.
+// Events that don't record the UID of the object they regard are taken to be about any object of
+// that name.
+func (e *Event) Regards(kind, namespace, name string, uid types.UID) bool {
+	if e.RegardingUID != "" {
+		return e.RegardingUID == uid
+	}
+	return e.Regarding.Kind == kind && e.Regarding.Namespace == namespace && e.Regarding.Name == name
+}
+
+// EventLog is every Event observed, with repetitions folded together. A repetition is either the
+// same Event object with a higher count (or series count), or a different Event object saying the
+// same thing about the same object. Only the maxLoggedEvents that happened most recently are kept.
+type EventLog struct {
+	events map[[6]string]*Event
+}
+
+// NewEventLog creates an empty EventLog.
+func NewEventLog() *EventLog {
+	return &EventLog{events: map[[6]string]*Event{}}
+}
+
+// Observe folds the Event object `o` into the log. It returns the Event `o` is part of, and
+// whether `o` is news, i.e., whether the Event is new, or has happened again. An Event object
+// observed again with the same count (e.g., when a watch is restarted) is not news.
+func (l *EventLog) Observe(o *unstructured.Unstructured) (e *Event, news bool) {
+	observed, count := newEvent(o)
+	e, exists := l.events[observed.key()]
+	if !exists {
+		e = observed
+		l.events[e.key()] = e
+		if len(l.events) > maxLoggedEvents {
+			l.forgetOldest()
+		}
+	}
+	if count <= e.counts[o.GetUID()] {
+		return e, false
+	}
+
+	e.Count += count - e.counts[o.GetUID()]
+	e.counts[o.GetUID()] = count
+	if first := observed.FirstTime; !first.IsZero() &&
+		(e.FirstTime.IsZero() || first.Before(e.FirstTime)) {
+		e.FirstTime = first
+	}
+	if observed.LastTime.After(e.LastTime) {
+		e.LastTime = observed.LastTime
+	}
+	return e, true
+}
+
+// forgetOldest removes the Event that happened least recently from the log.
+func (l *EventLog) forgetOldest() {
+	var oldest *Event
+	for _, e := range l.events {
+		if oldest == nil || e.LastTime.Before(oldest.LastTime) {
+			oldest = e
+		}
+	}
+	delete(l.events, oldest.key())
+}
+
+// Regarding returns the Events in the log that regard the object `o`, oldest first.
+func (l *EventLog) Regarding(o *unstructured.Unstructured) []*Event {
+	events := []*Event{}
+	for _, e := range l.events {
+		if e.Regards(o.GetKind(), o.GetNamespace(), o.GetName(), o.GetUID()) {
+			events = append(events, e)
+		}
+	}
+	sortEvents(events)
+	return events
+}
+
+// Recent returns the `n` Events in the log that happened most recently, oldest first.
+func (l *EventLog) Recent(n int) []*Event {
+	events := make([]*Event, 0, len(l.events))
+	for _, e := range l.events {
+		events = append(events, e)
+	}
+	sortEvents(events)
+	if len(events) > n {
+		events = events[len(events)-n:]
+	}
+	return events
+}
+
+// sortEvents sorts `events` by when they most recently happened, oldest first.
+func sortEvents(events []*Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].LastTime.Equal(events[j].LastTime) {
+			return events[i].LastTime.Before(events[j].LastTime)
+		}
+		// Break ties in a stable order, so that the same log always produces the same model.
+		ki, kj := events[i].key(), events[j].key()
+		for k := range ki {
+			if ki[k] != kj[k] {
+				return ki[k] < kj[k]
+			}
+		}
+		return false
+	})
+}
+
+// Family is a set of objects and every one of their descendants, as determined by the
+// `.metadata.ownerReferences` of each object observed. Objects that are deleted remain in the
+// family for forgetAfter, so that Events about them are still recognized.
+type Family struct {
+	roots  map[types.UID]bool
+	owners map[types.UID][]types.UID
+
+	// uids identifies each object observed by its kind, namespace, and name, for Events that don't
+	// record the UID of the object they regard.
+	uids map[[3]string]types.UID
+
+	// deleted are the objects deleted, in the order they were, to be forgotten after forgetAfter.
+	deleted []deletion
+	now     func() time.Time
+}
+
+type deletion struct {
+	uid  types.UID
+	name [3]string
+	at   time.Time
+}
+
+// NewFamily creates an empty Family.
+func NewFamily() *Family {
+	return &Family{
+		roots: map[types.UID]bool{}, owners: map[types.UID][]types.UID{},
+		uids: map[[3]string]types.UID{}, now: time.Now,
+	}
+}
+
+// AddRoot adds `o`, and every descendant of it, to the family.
+func (f *Family) AddRoot(o *unstructured.Unstructured) {
+	f.roots[o.GetUID()] = true
+	f.Observe(o)
+}
+
+// Observe records the owners of `o`, so that it is recognized as a member of the family if any
+// of them are.
+func (f *Family) Observe(o *unstructured.Unstructured) {
+	owners := []types.UID{}
+	for _, ref := range o.GetOwnerReferences() {
+		owners = append(owners, ref.UID)
+	}
+	f.owners[o.GetUID()] = owners
+	f.uids[[3]string{o.GetKind(), o.GetNamespace(), o.GetName()}] = o.GetUID()
+	f.forget()
+}
+
+// Delete records that `o` has been deleted, so that it is forgotten after forgetAfter.
+func (f *Family) Delete(o *unstructured.Unstructured) {
+	f.deleted = append(f.deleted, deletion{
+		uid: o.GetUID(), name: [3]string{o.GetKind(), o.GetNamespace(), o.GetName()}, at: f.now(),
+	})
+	f.forget()
+}
+
+// forget removes the objects deleted more than forgetAfter ago from the family.
+func (f *Family) forget() {
+	cutoff := f.now().Add(-forgetAfter)
+	for len(f.deleted) > 0 && f.deleted[0].at.Before(cutoff) {
+		d := f.deleted[0]
+		f.deleted = f.deleted[1:]
+		delete(f.roots, d.uid)
+		delete(f.owners, d.uid)
+		if f.uids[d.name] == d.uid {
+			delete(f.uids, d.name)
+		}
+	}
+}
+
+// Contains reports whether the object `uid` is a member of the family.
+func (f *Family) Contains(uid types.UID) bool {
+	seen := map[types.UID]bool{}
+	for queue := []types.UID{uid}; len(queue) > 0; queue = queue[1:] {
+		if f.roots[queue[0]] {
+			return true
+		} else if seen[queue[0]] {
+			continue
+		}
+		seen[queue[0]] = true
+		queue = append(queue, f.owners[queue[0]]...)
+	}
+	return false
+}
+
+// Regards reports whether the Event object `o` is about a member of the family.
+func (f *Family) Regards(o *unstructured.Unstructured) bool {
+	e, _ := newEvent(o)
+	uid := e.RegardingUID
+	if uid == "" {
+		uid = f.uids[[3]string{e.Regarding.Kind, e.Regarding.Namespace, e.Regarding.Name}]
+	}
+	return uid != "" && f.Contains(uid)
+}
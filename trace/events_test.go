@@ -0,0 +1,91 @@
+package trace
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// object is the object `kind` `default/name`, with UID `uid`, owned by the objects `owners`.
+func object(kind, name, uid string, owners ...string) *unstructured.Unstructured {
+	refs := []interface{}{}
+	for _, owner := range owners {
+		refs = append(refs, map[string]interface{}{"kind": "ReplicaSet", "name": owner, "uid": owner})
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name": name, "namespace": "default", "uid": uid, "ownerReferences": refs,
+		},
+	}}
+}
+
+// kubeEvent is a Kubernetes Event about the Pod `default/name`, which last happened at `at`.
+func kubeEvent(name, reason string, at time.Time) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Event",
+		"metadata":   map[string]interface{}{"name": name + "." + reason, "uid": name + reason},
+		"involvedObject": map[string]interface{}{
+			"kind": "Pod", "namespace": "default", "name": name,
+		},
+		"reason":        reason,
+		"lastTimestamp": at.Format(time.RFC3339),
+	}}
+}
+
+func TestFamilyForgetsDeleted(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFamily()
+	f.now = func() time.Time { return now }
+
+	f.AddRoot(object("ReplicaSet", "nginx", "rs"))
+	f.Observe(object("Pod", "nginx-a", "a", "rs"))
+	f.Observe(object("Pod", "nginx-b", "b", "rs"))
+	f.Delete(object("Pod", "nginx-a", "a", "rs"))
+
+	tests := []struct {
+		after   time.Duration
+		a, b    bool
+		aByName bool
+	}{
+		// Events about a deleted member are still recognized for a while...
+		{after: time.Minute, a: true, b: true, aByName: true},
+		// ...but not forever.
+		{after: forgetAfter + time.Minute, a: false, b: true, aByName: false},
+	}
+	for _, test := range tests {
+		now = now.Add(test.after)
+		f.Observe(object("Pod", "nginx-b", "b", "rs"))
+
+		if got := f.Contains("a"); got != test.a {
+			t.Errorf("After %s: Contains(a) = %v, want %v", test.after, got, test.a)
+		}
+		if got := f.Contains("b"); got != test.b {
+			t.Errorf("After %s: Contains(b) = %v, want %v", test.after, got, test.b)
+		}
+		if got := f.Regards(kubeEvent("nginx-a", "Killing", now)); got != test.aByName {
+			t.Errorf("After %s: Regards(Event about nginx-a) = %v, want %v", test.after, got,
+				test.aByName)
+		}
+	}
+}
+
+func TestEventLogForgetsOldest(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewEventLog()
+	for i := 0; i <= maxLoggedEvents; i++ {
+		at := start.Add(time.Duration(i) * time.Second)
+		l.Observe(kubeEvent(fmt.Sprintf("nginx-%d", i), "Scheduled", at))
+	}
+
+	if got := len(l.Recent(2 * maxLoggedEvents)); got != maxLoggedEvents {
+		t.Errorf("Log holds %d Events, want %d", got, maxLoggedEvents)
+	}
+	if got := l.Recent(maxLoggedEvents)[0].Regarding.Name; got != "nginx-1" {
+		t.Errorf("Oldest Event kept regards %s, want nginx-1", got)
+	}
+}
@@ -0,0 +1,183 @@
+package trace
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pulumi/kubespy/pods"
+	"github.com/pulumi/pulumi-kubernetes/provider/v4/pkg/openapi"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sWatch "k8s.io/apimachinery/pkg/watch"
+)
+
+// Service is the state of a Service, the Endpoints object or EndpointSlices that direct its
+// traffic, and the Pods it directs traffic to.
+type Service struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// Service is nil until the Service has been observed.
+	Service *Object `json:"service,omitempty"`
+	Type    string  `json:"type,omitempty"`
+	Checks  []Check `json:"checks"`
+
+	// EndpointObjects are the Endpoints object or EndpointSlices that direct traffic to Pods.
+	EndpointObjects []Object        `json:"endpointObjects"`
+	Endpoints       []pods.Endpoint `json:"endpoints"`
+	EndpointsCheck  *Check          `json:"endpointsCheck,omitempty"`
+
+	// Events are the most recent Kubernetes Events regarding the Service and the objects it owns,
+	// if they are being watched.
+	Events []*Event `json:"events,omitempty"`
+}
+
+// Kind implements Model.
+func (s *Service) Kind() string { return "Service" }
+
+// Verdict implements Model. A Service has succeeded once every check passes, including that it
+// directs traffic only to live Pods.
+func (s *Service) Verdict() Verdict {
+	if s.Service == nil || s.Service.EventType == k8sWatch.Deleted {
+		return Waiting
+	}
+
+	checks := s.Checks
+	if s.EndpointsCheck != nil {
+		checks = append(checks[:len(checks):len(checks)], *s.EndpointsCheck)
+	}
+	for _, c := range checks {
+		if c.Status != Success {
+			return Progressing
+		}
+	}
+	return Succeeded
+}
+
+// NewService computes the state of the Service `namespace/name` from `table`, which maps
+// `apiVersion/Kind` to the latest watch events observed for objects of that type.
+func NewService(namespace, name string, table map[string][]k8sWatch.Event) *Service {
+	s := &Service{
+		Namespace:       namespace,
+		Name:            name,
+		Checks:          []Check{},
+		EndpointObjects: []Object{},
+		Endpoints:       []pods.Endpoint{},
+	}
+
+	if events, hasSvc := table[ServiceKey]; hasSvc {
+		o := events[0].Object.(*unstructured.Unstructured)
+		s.Service = objectFromEvent(events[0])
+
+		svcTypeI, _ := openapi.Pluck(o.Object, "spec", "type")
+		var isString bool
+		s.Type, isString = svcTypeI.(string)
+		if !isString {
+			s.Type = "ClusterIP"
+		}
+
+		switch s.Type {
+		case "ClusterIP":
+			s.Checks = append(s.Checks, endpointsCreated(o, table))
+
+			clusterIPI, _ := openapi.Pluck(o.Object, "spec", "clusterIP")
+			if clusterIP, isString := clusterIPI.(string); isString && len(clusterIP) > 0 {
+				s.Checks = append(s.Checks,
+					check(Success, "Successfully allocated a cluster-internal IP: %s", Highlight(clusterIP)))
+			} else {
+				s.Checks = append(s.Checks, check(Failure, "Waiting for cluster-internal IP to be allocated"))
+			}
+		case "LoadBalancer":
+			s.Checks = append(s.Checks, endpointsCreated(o, table))
+
+			ingressesI, _ := openapi.Pluck(o.Object, "status", "loadBalancer", "ingress")
+			if ingresses, isMap := ingressesI.([]interface{}); isMap {
+				ips := []string{}
+				for _, ingressI := range ingresses {
+					if ingress, isMap := ingressI.(map[string]interface{}); isMap {
+						tmp := []string{}
+						if ip, isString := ingress["ip"].(string); isString {
+							tmp = append(tmp, ip)
+						}
+						if hostname, isString := ingress["hostname"].(string); isString {
+							tmp = append(tmp, hostname)
+						}
+						ips = append(ips, strings.Join(tmp, "/"))
+					}
+				}
+
+				if len(ips) > 0 {
+					sort.Strings(ips)
+					c := check(Success, "Service allocated the following IPs/hostnames:")
+					c.Items = ips
+					s.Checks = append(s.Checks, c)
+				}
+			} else {
+				s.Checks = append(s.Checks, check(Failure, "Waiting for public IP/host to be allocated"))
+			}
+		case "ExternalName":
+			externalNameI, _ := openapi.Pluck(o.Object, "spec", "externalName")
+			if externalName, isString := externalNameI.(string); isString && len(externalName) > 0 {
+				s.Checks = append(s.Checks, check(Success, "Service proxying to %s", Highlight(externalName)))
+			} else {
+				s.Checks = append(s.Checks,
+					check(Failure, "Service not given a URI to proxy to in `.spec.externalName`"))
+			}
+		}
+	}
+
+	if events := table[EndpointSliceKey]; len(events) > 0 {
+		slices := []*unstructured.Unstructured{}
+		for _, e := range events {
+			slices = append(slices, e.Object.(*unstructured.Unstructured))
+		}
+		sort.Slice(slices, func(i, j int) bool { return slices[i].GetName() < slices[j].GetName() })
+		for _, slice := range slices {
+			s.EndpointObjects = append(s.EndpointObjects,
+				*objectFromEvent(k8sWatch.Event{Type: k8sWatch.Added, Object: slice}))
+		}
+
+		s.Endpoints = pods.FromEndpointSlices(slices)
+		s.EndpointsCheck = trafficTargets(s.Endpoints)
+	} else if events, hasEPs := table[EndpointsKey]; hasEPs {
+		o := events[0].Object.(*unstructured.Unstructured)
+		s.EndpointObjects = append(s.EndpointObjects, *objectFromEvent(events[0]))
+
+		s.Endpoints = pods.FromEndpoints(o)
+		s.EndpointsCheck = trafficTargets(s.Endpoints)
+	} else if s.Type != "ExternalName" {
+		c := check(Failure, "Waiting for live Pods to be targeted by service")
+		s.EndpointsCheck = &c
+	}
+
+	return s
+}
+
+// endpointsCreated checks whether the Endpoints object or EndpointSlices that direct traffic for the
+// Service `o` have been created.
+func endpointsCreated(o *unstructured.Unstructured, table map[string][]k8sWatch.Event) Check {
+	if slices := table[EndpointSliceKey]; len(slices) > 0 {
+		return check(Success, "Successfully created %d EndpointSlice(s) for '%s' to direct traffic to Pods",
+			len(slices), Highlight(o.GetName()))
+	} else if eps, hasEndpoints := table[EndpointsKey]; hasEndpoints && eps[0].Type != k8sWatch.Deleted {
+		return check(Success, "Successfully created Endpoints object '%s' to direct traffic to Pods",
+			Highlight(o.GetName()))
+	}
+	return check(Failure, "Waiting for Endpoints object to be created, to direct traffic to Pods")
+}
+
+func trafficTargets(endpoints []pods.Endpoint) *Check {
+	allReady := true
+	for _, endpoint := range endpoints {
+		allReady = allReady && endpoint.Ready
+	}
+
+	var c Check
+	if len(endpoints) == 0 {
+		c = check(Failure, "Does not direct traffic to any Pods")
+	} else if !allReady {
+		c = check(Failure, "Directs traffic to the following live Pods:")
+	} else {
+		c = check(Success, "Directs traffic to the following live Pods:")
+	}
+	return &c
+}
@@ -0,0 +1,188 @@
+package trace
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	k8sWatch "k8s.io/apimachinery/pkg/watch"
+)
+
+// maxPendingKubeEvents is how many Kubernetes Events a Snapshotter holds back, waiting for the
+// object they regard to be observed, before the oldest are dropped.
+const maxPendingKubeEvents = 100
+
+// Snapshotter computes the trace of one object from a stream of watch events of every object of
+// interest, e.g., as recorded. This is how traces are computed from a recording rather than from
+// live watches: Deployments and Services are traced as `kubespy trace` would trace them, and any
+// other kind of object as `kubespy tree` would. The state of every object is updated with each
+// event, so that the trace after each one costs only as much as the objects that exist then.
+//
+// Any Kubernetes Events that regard the traced object or its descendants are included in the
+// trace. An Event may be observed before the object it regards, so Events that regard no known
+// object are held back until they do.
+type Snapshotter struct {
+	apiVersion, kind, namespace, name string
+	eventsFiltered                    bool
+
+	// root is the traced object, and objects every object that exists, keyed by UID.
+	root    *unstructured.Unstructured
+	objects map[types.UID]*unstructured.Unstructured
+
+	// tables hold the latest watch event of each object that NewDeployment or NewService traces,
+	// keyed by table key, then UID.
+	tables map[string]map[types.UID]k8sWatch.Event
+
+	family     *Family
+	kubeEvents *EventLog
+	pending    []*unstructured.Unstructured
+}
+
+// NewSnapshotter creates a Snapshotter of the object `namespace/name`. If `eventsFiltered` is true,
+// every Kubernetes Event observed is known to regard the traced object or its descendants (e.g.,
+// because `kubespy record --events` recorded only those), and is included even if the descendant it
+// regards is never observed.
+func NewSnapshotter(apiVersion, kind, namespace, name string, eventsFiltered bool) *Snapshotter {
+	return &Snapshotter{
+		apiVersion: apiVersion, kind: kind, namespace: namespace, name: name,
+		eventsFiltered: eventsFiltered,
+		objects:        map[types.UID]*unstructured.Unstructured{},
+		tables:         map[string]map[types.UID]k8sWatch.Event{},
+		family:         NewFamily(),
+		kubeEvents:     NewEventLog(),
+	}
+}
+
+// Observe updates the state of the object in `e`.
+func (s *Snapshotter) Observe(e k8sWatch.Event) {
+	o := e.Object.(*unstructured.Unstructured)
+	if o.GetNamespace() != s.namespace {
+		return
+	}
+
+	if IsEvent(o) {
+		// Events are deleted once they expire, which is not itself news.
+		if e.Type == k8sWatch.Deleted {
+			return
+		}
+		if s.eventsFiltered || s.family.Regards(o) {
+			s.kubeEvents.Observe(o)
+			return
+		}
+		s.pending = append(s.pending, o)
+		if len(s.pending) > maxPendingKubeEvents {
+			s.pending = s.pending[len(s.pending)-maxPendingKubeEvents:]
+		}
+		return
+	}
+
+	isRoot := o.GetKind() == s.kind && o.GetName() == s.name
+	_, known := s.objects[o.GetUID()]
+	if isRoot {
+		s.family.AddRoot(o)
+	} else {
+		s.family.Observe(o)
+	}
+	if !known && len(s.pending) > 0 {
+		s.retryPending()
+	}
+
+	deleted := e.Type == k8sWatch.Deleted
+	if deleted {
+		s.family.Delete(o)
+		delete(s.objects, o.GetUID())
+	} else {
+		s.objects[o.GetUID()] = o
+	}
+	if isRoot {
+		s.root = o
+		if deleted {
+			s.root = nil
+		}
+	}
+
+	// The traced object stays in its table once deleted, so that the trace can say so.
+	switch {
+	case s.kind == "Deployment" && isRoot:
+		s.tables[DeploymentKey] = map[types.UID]k8sWatch.Event{o.GetUID(): e}
+	case s.kind == "Deployment" && o.GetKind() == "ReplicaSet":
+		s.set(ReplicaSetKey, e, !deleted && ownedByDeployment(o, s.name))
+	case s.kind == "Deployment" && o.GetKind() == "Pod":
+		s.set(PodKey, e, !deleted)
+	case s.kind == "Service" && isRoot:
+		s.tables[ServiceKey] = map[types.UID]k8sWatch.Event{o.GetUID(): e}
+	case s.kind == "Service" && o.GetKind() == "Endpoints" && o.GetName() == s.name:
+		s.set(EndpointsKey, e, !deleted)
+	case s.kind == "Service" && o.GetKind() == "EndpointSlice":
+		s.set(EndpointSliceKey, e,
+			!deleted && o.GetLabels()["kubernetes.io/service-name"] == s.name)
+	}
+}
+
+// set records `e` as the latest event of its object in the table `key` if `include` is true, and
+// otherwise removes the object from it (e.g., because it was deleted).
+func (s *Snapshotter) set(key string, e k8sWatch.Event, include bool) {
+	uid := e.Object.(*unstructured.Unstructured).GetUID()
+	if !include {
+		delete(s.tables[key], uid)
+		return
+	}
+	if s.tables[key] == nil {
+		s.tables[key] = map[types.UID]k8sWatch.Event{}
+	}
+	s.tables[key][uid] = e
+}
+
+// retryPending folds the Kubernetes Events held back into the trace, if they now regard the traced
+// object or its descendants.
+func (s *Snapshotter) retryPending() {
+	held := []*unstructured.Unstructured{}
+	for _, o := range s.pending {
+		if s.family.Regards(o) {
+			s.kubeEvents.Observe(o)
+		} else {
+			held = append(held, o)
+		}
+	}
+	s.pending = held
+}
+
+// Model computes the trace after every event observed so far.
+func (s *Snapshotter) Model() Model {
+	// Iterate in a stable order, so that the same events always produce the same model.
+	table := map[string][]k8sWatch.Event{} // apiVersion/Kind -> []k8sWatch.Event
+	for key, events := range s.tables {
+		uids := make([]string, 0, len(events))
+		for uid := range events {
+			uids = append(uids, string(uid))
+		}
+		sort.Strings(uids)
+		for _, uid := range uids {
+			table[key] = append(table[key], events[types.UID(uid)])
+		}
+	}
+
+	switch s.kind {
+	case "Deployment":
+		d := NewDeployment(s.namespace, s.name, table, s.kubeEvents, nil)
+		d.Events = s.kubeEvents.Recent(MaxEvents)
+		return d
+	case "Service":
+		svc := NewService(s.namespace, s.name, table)
+		svc.Events = s.kubeEvents.Recent(MaxEvents)
+		return svc
+	default:
+		t := NewTree(s.apiVersion, s.kind, s.namespace, s.name, s.root, s.objects)
+		t.Events = s.kubeEvents.Recent(MaxEvents)
+		return t
+	}
+}
+
+func ownedByDeployment(o *unstructured.Unstructured, name string) bool {
+	for _, ref := range o.GetOwnerReferences() {
+		if ref.Kind == "Deployment" && ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
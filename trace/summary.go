@@ -0,0 +1,160 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RolloutSummary summarizes the rollout of a Deployment over the course of a trace, e.g., for
+// posting to the pull request that triggered it.
+type RolloutSummary struct {
+	Namespace string  `json:"namespace"`
+	Name      string  `json:"name"`
+	Revision  int     `json:"revision"`
+	Verdict   Verdict `json:"verdict"`
+
+	// Started is when the rollout was first observed, and Finished is when it first reached its
+	// final verdict. Finished is zero if the rollout has not yet finished.
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished,omitempty"`
+
+	// MaxUnavailable is the largest number of the SpecReplicas desired Pods that were unavailable at
+	// any point during the rollout.
+	SpecReplicas   int64 `json:"specReplicas"`
+	MaxUnavailable int64 `json:"maxUnavailable"`
+
+	// FailedPods are the Pods that failed at any point during the rollout, in the order they first
+	// failed.
+	FailedPods []PodFailure `json:"failedPods"`
+
+	// Conditions are the messages of the Deployment's checks when the rollout finished.
+	Conditions []string `json:"conditions"`
+}
+
+// PodFailure explains every way a single Pod failed during a rollout.
+type PodFailure struct {
+	Name     string   `json:"name"`
+	Reasons  []string `json:"reasons"`
+	Restarts int64    `json:"restarts"`
+}
+
+// NewRolloutSummary creates an empty summary of the rollout of the Deployment `namespace/name`.
+func NewRolloutSummary(namespace, name string) *RolloutSummary {
+	return &RolloutSummary{
+		Namespace:  namespace,
+		Name:       name,
+		Verdict:    Waiting,
+		FailedPods: []PodFailure{},
+		Conditions: []string{},
+	}
+}
+
+// Duration is how long the rollout took, or has taken so far.
+func (s *RolloutSummary) Duration() time.Duration {
+	if s.Started.IsZero() || s.Finished.IsZero() {
+		return 0
+	}
+	return s.Finished.Sub(s.Started)
+}
+
+// Done is true once the rollout has succeeded or failed.
+func (s *RolloutSummary) Done() bool {
+	return s.Verdict == Succeeded || s.Verdict == Failed
+}
+
+// Observe updates the summary with the state `d` of the Deployment's trace at time `t`. States
+// observed after the rollout is done are ignored.
+func (s *RolloutSummary) Observe(t time.Time, d *Deployment) {
+	if s.Done() || d.Deployment == nil {
+		return
+	}
+
+	if s.Started.IsZero() {
+		s.Started = t
+	}
+	s.Revision = d.Revision
+	s.Verdict = d.Verdict()
+	if s.Done() {
+		s.Finished = t
+	}
+
+	s.SpecReplicas = d.SpecReplicas
+	unavailable := d.SpecReplicas - d.AvailableReplicas
+	if d.RollingOut && unavailable > s.MaxUnavailable {
+		s.MaxUnavailable = unavailable
+	}
+
+	for _, rs := range []*ReplicaSet{d.Current, d.Previous} {
+		if rs == nil {
+			continue
+		}
+		for _, pod := range rs.Pods {
+			s.observePod(pod, s.Verdict == Failed)
+		}
+	}
+
+	s.Conditions = []string{}
+	for _, c := range d.Checks {
+		s.Conditions = append(s.Conditions, c.Message())
+	}
+}
+
+// observePod records the ways `pod` is failing. Every new Pod is briefly unready while it starts, so
+// the reasons its conditions aren't true are recorded only if the rollout has failed, since they
+// might be why.
+func (s *RolloutSummary) observePod(pod Pod, failed bool) {
+	reasons := []string{}
+	for _, problem := range pod.Conditions {
+		if failed || problem.Reason == "Unschedulable" {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", problem.Reason, problem.Message))
+		}
+	}
+	for _, c := range pod.Containers {
+		if c.Problem != nil {
+			reason := fmt.Sprintf("%s container %s: %s", c.Type, c.Name, c.Problem.Reason)
+			// The kubelet's CrashLoopBackOff message just repeats the back-off delay.
+			if c.Problem.Message != "" && c.Problem.Reason != "CrashLoopBackOff" {
+				reason += " " + strings.TrimSpace(c.Problem.Message)
+			}
+			reasons = append(reasons, reason)
+		}
+		if t := c.LastTermination; t != nil && c.RestartCount > 0 && t.ExitCode != 0 {
+			reasons = append(reasons, fmt.Sprintf("%s container %s exited: %s (code %d)",
+				c.Type, c.Name, t.Reason, t.ExitCode))
+		}
+	}
+	if len(reasons) == 0 {
+		return
+	}
+
+	i := len(s.FailedPods)
+	for j, failure := range s.FailedPods {
+		if failure.Name == pod.Name {
+			i = j
+			break
+		}
+	}
+	if i == len(s.FailedPods) {
+		s.FailedPods = append(s.FailedPods, PodFailure{Name: pod.Name, Reasons: []string{}})
+	}
+
+	failure := &s.FailedPods[i]
+	for _, reason := range reasons {
+		if !contains(failure.Reasons, reason) {
+			failure.Reasons = append(failure.Reasons, reason)
+		}
+	}
+	if restarts := pod.Restarts(); restarts > failure.Restarts {
+		failure.Restarts = restarts
+	}
+}
+
+func contains(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
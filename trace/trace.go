@@ -0,0 +1,116 @@
+// Package trace computes the state of "complex" Kubernetes resources (e.g., a Service and the
+// Endpoints and Pods it directs traffic to) from the watch events of their constituent objects. Each
+// trace produces a Model, which the renderers in the print package display.
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sWatch "k8s.io/apimachinery/pkg/watch"
+)
+
+// The keys of the tables that NewService and NewDeployment compute traces from, which identify the
+// type of the objects whose watch events they hold.
+const (
+	EndpointsKey     = "v1/Endpoints"
+	EndpointSliceKey = "discovery.k8s.io/v1/EndpointSlice"
+	ServiceKey       = "v1/Service"
+	PodKey           = "v1/Pod"
+	DeploymentKey    = "Deployment"
+	ReplicaSetKey    = "v1/ReplicaSet"
+)
+
+const trueStatus = "True"
+
+// Model is the state of a trace at a point in time.
+type Model interface {
+	// Kind is the kind of trace that produced the model, e.g., `Service`.
+	Kind() string
+
+	// Verdict summarizes the state of the traced object.
+	Verdict() Verdict
+}
+
+// Verdict summarizes the state of a traced object, e.g., for bots that only care whether a rollout
+// has finished.
+type Verdict string
+
+const (
+	// Waiting means the traced object has not yet been observed.
+	Waiting Verdict = "waiting"
+	// Progressing means the traced object exists, but is not yet ready.
+	Progressing Verdict = "progressing"
+	// Succeeded means the traced object is ready, e.g., a Deployment has finished rolling out.
+	Succeeded Verdict = "succeeded"
+	// Failed means the traced object will not become ready without intervention, e.g., a
+	// Deployment's rollout has exceeded its progress deadline.
+	Failed Verdict = "failed"
+)
+
+// Object identifies an API object, and the type of the last watch event observed for it.
+type Object struct {
+	EventType  k8sWatch.EventType `json:"eventType,omitempty"`
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Namespace  string             `json:"namespace,omitempty"`
+	Name       string             `json:"name"`
+}
+
+func objectFromEvent(e k8sWatch.Event) *Object {
+	o := e.Object.(*unstructured.Unstructured)
+	return &Object{
+		EventType:  e.Type,
+		APIVersion: o.GetAPIVersion(),
+		Kind:       o.GetKind(),
+		Namespace:  o.GetNamespace(),
+		Name:       o.GetName(),
+	}
+}
+
+// Status is the outcome of a Check.
+type Status string
+
+const (
+	// Success means the check has passed.
+	Success Status = "success"
+	// Failure means the check has failed, or has not yet passed.
+	Failure Status = "failure"
+	// Pending means the check is making progress towards passing.
+	Pending Status = "pending"
+)
+
+// Check is a single fact about the state of a traced object, e.g., "Deployment is available".
+type Check struct {
+	Status Status `json:"status"`
+
+	// Format and Args make up the message describing the check. They are kept separate, so that
+	// renderers can emphasize arguments of type Highlight (typically names, IPs, and the like).
+	Format string        `json:"-"`
+	Args   []interface{} `json:"-"`
+
+	// Items is an optional list of things the check refers to, e.g., a list of allocated IPs.
+	Items []string `json:"items,omitempty"`
+}
+
+// Highlight marks an argument of a Check as something renderers should emphasize.
+type Highlight string
+
+// Message is the plain-text message describing the check.
+func (c Check) Message() string {
+	return fmt.Sprintf(c.Format, c.Args...)
+}
+
+// MarshalJSON includes the check's plain-text message.
+func (c Check) MarshalJSON() ([]byte, error) {
+	type plainCheck Check
+	return json.Marshal(struct {
+		plainCheck
+		Message string `json:"message"`
+	}{plainCheck(c), c.Message()})
+}
+
+func check(status Status, format string, args ...interface{}) Check {
+	return Check{Status: status, Format: format, Args: args}
+}
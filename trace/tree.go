@@ -0,0 +1,111 @@
+package trace
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pulumi/kubespy/k8sobject"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Tree is the state of an object and every one of its descendants, as determined by the
+// `.metadata.ownerReferences` of each object.
+type Tree struct {
+	APIVersion string `json:"apiVersion"`
+	ObjectKind string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+
+	// Root is nil until the root object has been observed.
+	Root *TreeNode `json:"root,omitempty"`
+
+	// Events are the most recent Kubernetes Events regarding the objects in the tree, if they are
+	// being watched.
+	Events []*Event `json:"events,omitempty"`
+}
+
+// Kind implements Model.
+func (t *Tree) Kind() string { return "Tree" }
+
+// Verdict implements Model. A tree has succeeded once its root is ready. Objects whose readiness
+// can't be determined are considered ready once they exist.
+func (t *Tree) Verdict() Verdict {
+	if t.Root == nil {
+		return Waiting
+	} else if t.Root.Ready == trueStatus || t.Root.Ready == "" {
+		return Succeeded
+	}
+	return Progressing
+}
+
+// TreeNode is a single object in a Tree.
+type TreeNode struct {
+	Object
+	UID types.UID `json:"uid"`
+
+	// Ready is the status of the object's `Ready` (or `Available`) condition, or empty if readiness
+	// can't be determined. See `k8sobject.Readiness`.
+	Ready   string    `json:"ready,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+	Created time.Time `json:"created"`
+
+	Children []*TreeNode `json:"children"`
+}
+
+// NewTree computes the tree rooted at `root` from `objects`. `root` may be nil if it has not yet
+// been observed.
+func NewTree(
+	apiVersion, kind, namespace, name string, root *unstructured.Unstructured,
+	objects map[types.UID]*unstructured.Unstructured,
+) *Tree {
+	t := &Tree{APIVersion: apiVersion, ObjectKind: kind, Namespace: namespace, Name: name}
+	if root == nil {
+		return t
+	}
+
+	children := map[types.UID][]*unstructured.Unstructured{}
+	for _, o := range objects {
+		for _, ref := range o.GetOwnerReferences() {
+			children[ref.UID] = append(children[ref.UID], o)
+		}
+	}
+	for _, siblings := range children {
+		sort.Slice(siblings, func(i, j int) bool {
+			if siblings[i].GetKind() != siblings[j].GetKind() {
+				return siblings[i].GetKind() < siblings[j].GetKind()
+			}
+			return siblings[i].GetName() < siblings[j].GetName()
+		})
+	}
+
+	visited := map[types.UID]bool{}
+	var newNode func(o *unstructured.Unstructured) *TreeNode
+	newNode = func(o *unstructured.Unstructured) *TreeNode {
+		visited[o.GetUID()] = true
+
+		node := &TreeNode{
+			Object: Object{
+				APIVersion: o.GetAPIVersion(),
+				Kind:       o.GetKind(),
+				Namespace:  o.GetNamespace(),
+				Name:       o.GetName(),
+			},
+			UID:      o.GetUID(),
+			Created:  o.GetCreationTimestamp().Time,
+			Children: []*TreeNode{},
+		}
+		node.Ready, node.Reason = k8sobject.Readiness(o)
+
+		for _, child := range children[o.GetUID()] {
+			// Ownership cycles should not exist, but a misbehaving controller should not hang us.
+			if !visited[child.GetUID()] {
+				node.Children = append(node.Children, newNode(child))
+			}
+		}
+		return node
+	}
+	t.Root = newNode(root)
+
+	return t
+}
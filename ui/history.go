@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pulumi/kubespy/print"
+	"github.com/pulumi/pulumi-kubernetes/provider/v4/pkg/openapi"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sWatch "k8s.io/apimachinery/pkg/watch"
+)
+
+// maxHistory is the number of entries kept per object, so that a long session with a chatty
+// controller does not grow without bound.
+const maxHistory = 200
+
+// entry is one observed change to an object.
+type entry struct {
+	time            time.Time
+	eventType       k8sWatch.EventType
+	resourceVersion string
+
+	// changes is the diff of the whole object, and status is the diff of its `.status`. Either is
+	// empty if that part of the object did not change.
+	changes string
+	status  string
+}
+
+// history is the sequence of changes observed for a single object.
+type history struct {
+	last    *unstructured.Unstructured
+	entries []entry
+}
+
+// record appends the change described by `e` to the history. Events that were already recorded
+// (e.g., those of the root object, which is observed by two watches) are ignored.
+func (h *history) record(e k8sWatch.Event) error {
+	o := e.Object.(*unstructured.Unstructured)
+	if e.Type == k8sWatch.Deleted && h.last == nil {
+		return nil
+	} else if h.last != nil && e.Type != k8sWatch.Deleted &&
+		h.last.GetResourceVersion() == o.GetResourceVersion() {
+		return nil
+	}
+
+	ent := entry{time: time.Now(), eventType: e.Type, resourceVersion: o.GetResourceVersion()}
+
+	switch {
+	case e.Type == k8sWatch.Deleted:
+		ent.changes, ent.status = "Object deleted", "Object deleted"
+	case h.last == nil:
+		var err error
+		if ent.changes, err = prettyJSON(o.Object); err != nil {
+			return err
+		}
+		if status, hasStatus := o.Object["status"]; hasStatus {
+			if ent.status, err = prettyJSON(status); err != nil {
+				return err
+			}
+		}
+	default:
+		var err error
+		if ent.changes, _, err = print.ObjectDiff(h.last.Object, o.Object); err != nil {
+			return err
+		}
+		if ent.status, _, err = print.ObjectDiff(statusOf(h.last), statusOf(o)); err != nil {
+			return err
+		}
+	}
+
+	if e.Type == k8sWatch.Deleted {
+		h.last = nil
+	} else {
+		h.last = o
+	}
+
+	h.entries = append(h.entries, ent)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+	return nil
+}
+
+func statusOf(o *unstructured.Unstructured) map[string]interface{} {
+	statusI, _ := openapi.Pluck(o.Object, "status")
+	status, _ := statusI.(map[string]interface{})
+	if status == nil {
+		return map[string]interface{}{}
+	}
+	return status
+}
+
+func prettyJSON(v interface{}) (string, error) {
+	j, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("Unable to serialize object: %v", err)
+	}
+	return string(j), nil
+}
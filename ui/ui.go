@@ -0,0 +1,598 @@
+// Package ui implements `kubespy ui`, a full-screen terminal UI for exploring an object, the tree of
+// objects it owns, and the history of changes to each of them.
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/pulumi/kubespy/print"
+	"github.com/pulumi/kubespy/trace"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	k8sWatch "k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/yaml"
+)
+
+// Screens of the UI.
+type screen int
+
+const (
+	treeScreen screen = iota
+	changesScreen
+	statusScreen
+)
+
+const (
+	headerHeight = 2
+	footerHeight = 2
+)
+
+var (
+	titleStyle    lipgloss.Style
+	selectedStyle lipgloss.Style
+	faintStyle    lipgloss.Style
+	readyStyle    lipgloss.Style
+	notReadyStyle lipgloss.Style
+	unknownStyle  lipgloss.Style
+	pausedStyle   lipgloss.Style
+	entryStyle    lipgloss.Style
+)
+
+// setStyles styles the UI consistently with the rest of kubespy's output. See `print.SetTheme`.
+func setStyles(theme print.Theme) {
+	if !print.ColorEnabled() {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	// Yellow and cyan are illegible on light backgrounds, so use magenta and blue instead.
+	warning, name := lipgloss.Color("3"), lipgloss.Color("6")
+	if theme.Palette == print.PaletteLight {
+		warning, name = lipgloss.Color("5"), lipgloss.Color("4")
+	}
+
+	titleStyle = lipgloss.NewStyle().Bold(true).Foreground(name)
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	faintStyle = lipgloss.NewStyle().Faint(true)
+	readyStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	notReadyStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("1"))
+	unknownStyle = lipgloss.NewStyle().Foreground(warning)
+	pausedStyle = lipgloss.NewStyle().Bold(true).Foreground(warning)
+	entryStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("4"))
+}
+
+// Run displays the UI for the object `namespace/name`, fed by `rootEvents`, which are the events of
+// the object itself, and `objectEvents`, which are the events of every object that might be one of
+// its descendants. It blocks until the user quits.
+func Run(
+	apiVersion, kind, namespace, name string, rootEvents, objectEvents <-chan k8sWatch.Event,
+) error {
+	setStyles(print.CurrentTheme())
+
+	_, err := tea.NewProgram(
+		newModel(apiVersion, kind, namespace, name, rootEvents, objectEvents),
+		tea.WithAltScreen(),
+	).Run()
+	return err
+}
+
+type eventMsg struct {
+	root  bool
+	event k8sWatch.Event
+}
+
+type tickMsg time.Time
+
+// row is a single line of the flattened ownership tree.
+type row struct {
+	node   *trace.TreeNode
+	prefix string
+}
+
+type model struct {
+	apiVersion, kind, namespace, name string
+	rootEvents, objectEvents          <-chan k8sWatch.Event
+
+	root    *unstructured.Unstructured
+	objects map[types.UID]*unstructured.Unstructured
+	rows    []row
+
+	// histories are those of the objects in the tree. unrecorded is the latest watch event of every
+	// other object, with which its history begins if it joins the tree.
+	histories  map[types.UID]*history
+	unrecorded map[types.UID]k8sWatch.Event
+
+	screen   screen
+	cursor   int
+	selected types.UID
+	viewport viewport.Model
+	content  string
+
+	// While paused, events are buffered rather than applied, so that the display holds still.
+	paused  bool
+	pending []eventMsg
+
+	searching bool
+	search    textinput.Model
+	query     string
+
+	width, height int
+	message       string
+}
+
+func newModel(
+	apiVersion, kind, namespace, name string, rootEvents, objectEvents <-chan k8sWatch.Event,
+) *model {
+	search := textinput.New()
+	search.Prompt = "/"
+
+	return &model{
+		apiVersion:   apiVersion,
+		kind:         kind,
+		namespace:    namespace,
+		name:         name,
+		rootEvents:   rootEvents,
+		objectEvents: objectEvents,
+		objects:      map[types.UID]*unstructured.Unstructured{},
+		histories:    map[types.UID]*history{},
+		unrecorded:   map[types.UID]k8sWatch.Event{},
+		viewport:     viewport.New(0, 0),
+		search:       search,
+	}
+}
+
+func (m *model) Init() tea.Cmd {
+	return tea.Batch(waitFor(m.rootEvents, true), waitFor(m.objectEvents, false), tick())
+}
+
+func waitFor(events <-chan k8sWatch.Event, root bool) tea.Cmd {
+	return func() tea.Msg {
+		return eventMsg{root: root, event: <-events}
+	}
+}
+
+// tick re-renders periodically even if nothing changes, so that ages stay current.
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.viewport.Width = msg.Width
+		m.viewport.Height = max(msg.Height-headerHeight-footerHeight, 1)
+		return m, nil
+	case tickMsg:
+		return m, tick()
+	case eventMsg:
+		next := waitFor(m.objectEvents, false)
+		if msg.root {
+			next = waitFor(m.rootEvents, true)
+		}
+		if m.paused {
+			m.pending = append(m.pending, msg)
+		} else {
+			m.apply(msg)
+			m.refresh()
+		}
+		return m, next
+	case tea.KeyMsg:
+		if m.searching {
+			return m.updateSearch(msg)
+		}
+		return m.updateKey(msg)
+	}
+	return m, nil
+}
+
+func (m *model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.searching = false
+		m.search.Blur()
+		m.query = m.search.Value()
+		m.findNext()
+		return m, nil
+	case "esc":
+		m.searching = false
+		m.search.Blur()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	return m, cmd
+}
+
+func (m *model) updateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.message = ""
+
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "p":
+		m.paused = !m.paused
+		if !m.paused {
+			for _, e := range m.pending {
+				m.apply(e)
+			}
+			m.pending = nil
+			m.refresh()
+		}
+		return m, nil
+	case "/":
+		m.searching = true
+		m.search.SetValue("")
+		return m, m.search.Focus()
+	case "n":
+		m.findNext()
+		return m, nil
+	case "y":
+		m.copyYAML()
+		return m, nil
+	}
+
+	if m.screen == treeScreen {
+		switch msg.String() {
+		case "up", "k":
+			m.moveCursor(m.cursor - 1)
+		case "down", "j":
+			m.moveCursor(m.cursor + 1)
+		case "home", "g":
+			m.moveCursor(0)
+		case "end", "G":
+			m.moveCursor(len(m.rows) - 1)
+		case "enter", "c":
+			m.open(changesScreen)
+		case "s":
+			m.open(statusScreen)
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "esc", "backspace", "left", "h":
+		m.screen = treeScreen
+		return m, nil
+	case "tab":
+		if m.screen == changesScreen {
+			m.open(statusScreen)
+		} else {
+			m.open(changesScreen)
+		}
+		return m, nil
+	case "c":
+		m.open(changesScreen)
+		return m, nil
+	case "s":
+		m.open(statusScreen)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+// apply updates the object state and histories with a single watch event.
+func (m *model) apply(msg eventMsg) {
+	o := msg.event.Object.(*unstructured.Unstructured)
+	if msg.root {
+		if msg.event.Type == k8sWatch.Deleted {
+			m.root = nil
+		} else {
+			m.root = o
+		}
+	} else if msg.event.Type == k8sWatch.Deleted {
+		delete(m.objects, o.GetUID())
+	} else {
+		m.objects[o.GetUID()] = o
+	}
+
+	h, hasHistory := m.histories[o.GetUID()]
+	switch {
+	case msg.event.Type == k8sWatch.Deleted:
+		delete(m.histories, o.GetUID())
+		delete(m.unrecorded, o.GetUID())
+	case hasHistory:
+		m.record(h, msg.event)
+	default:
+		m.unrecorded[o.GetUID()] = msg.event
+	}
+}
+
+// record records `e` in the history `h`.
+func (m *model) record(h *history, e k8sWatch.Event) {
+	if err := h.record(e); err != nil {
+		m.message = err.Error()
+	}
+}
+
+// keepHistories keeps the histories of the objects in the tree, and only those: objects that join
+// the tree begin theirs with their latest state, and those that leave it lose theirs.
+func (m *model) keepHistories() {
+	inTree := map[types.UID]bool{}
+	for _, r := range m.rows {
+		uid := r.node.UID
+		inTree[uid] = true
+		if e, isUnrecorded := m.unrecorded[uid]; isUnrecorded {
+			h := &history{}
+			m.record(h, e)
+			m.histories[uid] = h
+			delete(m.unrecorded, uid)
+		}
+	}
+	for uid, h := range m.histories {
+		if !inTree[uid] {
+			if h.last != nil {
+				m.unrecorded[uid] = k8sWatch.Event{Type: k8sWatch.Modified, Object: h.last}
+			}
+			delete(m.histories, uid)
+		}
+	}
+}
+
+// refresh recomputes the tree and the contents of the detail view after the state changes.
+func (m *model) refresh() {
+	t := trace.NewTree(m.apiVersion, m.kind, m.namespace, m.name, m.root, m.objects)
+
+	glyphs := print.CurrentTheme().Glyphs
+	m.rows = nil
+	if t.Root != nil {
+		var addNode func(node *trace.TreeNode, indent, branch string)
+		addNode = func(node *trace.TreeNode, indent, branch string) {
+			m.rows = append(m.rows, row{node: node, prefix: indent + branch})
+
+			childIndent := indent
+			switch branch {
+			case glyphs.LastBranch:
+				childIndent += strings.Repeat(" ", lipgloss.Width(branch))
+			case glyphs.Branch:
+				childIndent += glyphs.Pipe
+			}
+			for i, child := range node.Children {
+				if i == len(node.Children)-1 {
+					addNode(child, childIndent, glyphs.LastBranch)
+				} else {
+					addNode(child, childIndent, glyphs.Branch)
+				}
+			}
+		}
+		addNode(t.Root, "", "")
+	}
+	m.keepHistories()
+
+	// Keep the cursor on the selected object, even if rows were added or removed above it.
+	cursor := -1
+	for i, r := range m.rows {
+		if r.node.UID == m.selected {
+			cursor = i
+			break
+		}
+	}
+	if cursor == -1 {
+		cursor = m.cursor
+	}
+	m.moveCursor(cursor)
+
+	if m.screen != treeScreen {
+		m.setDetailContent(false)
+	}
+}
+
+func (m *model) moveCursor(cursor int) {
+	if cursor >= len(m.rows) {
+		cursor = len(m.rows) - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	m.cursor = cursor
+	if cursor < len(m.rows) {
+		m.selected = m.rows[cursor].node.UID
+	}
+}
+
+func (m *model) open(s screen) {
+	if len(m.rows) == 0 {
+		return
+	}
+	m.screen = s
+	m.setDetailContent(true)
+}
+
+// setDetailContent renders the history of the selected object into the viewport. The viewport
+// follows new entries only if it was already scrolled to the bottom, so that reading scrollback is
+// not interrupted.
+func (m *model) setDetailContent(reset bool) {
+	follow := reset || m.viewport.AtBottom()
+
+	var b strings.Builder
+	if h, hasHistory := m.histories[m.selected]; hasHistory {
+		for _, e := range h.entries {
+			text := e.changes
+			if m.screen == statusScreen {
+				text = e.status
+			}
+			if text == "" {
+				continue
+			}
+			fmt.Fprintf(&b, "%s\n%s\n\n", entryStyle.Render(fmt.Sprintf(
+				"─── %s %s (resourceVersion %s) ───",
+				e.time.Format("15:04:05"), e.eventType, e.resourceVersion)), text)
+		}
+	}
+	if b.Len() == 0 {
+		b.WriteString(faintStyle.Render("No changes observed yet"))
+	}
+
+	m.content = b.String()
+	m.viewport.SetContent(m.content)
+	if follow {
+		m.viewport.GotoBottom()
+	}
+}
+
+// findNext moves to the next match of the search query: the next object whose kind or name
+// contains it in the tree, or the next line containing it in the detail view.
+func (m *model) findNext() {
+	query := strings.ToLower(m.query)
+	if query == "" {
+		return
+	}
+
+	if m.screen == treeScreen {
+		for i := 1; i <= len(m.rows); i++ {
+			r := m.rows[(m.cursor+i)%len(m.rows)]
+			if strings.Contains(strings.ToLower(r.node.Kind+"/"+r.node.Name), query) {
+				m.moveCursor((m.cursor + i) % len(m.rows))
+				return
+			}
+		}
+	} else {
+		lines := strings.Split(stripANSI(m.content), "\n")
+		for i := 1; i <= len(lines); i++ {
+			line := (m.viewport.YOffset + i) % len(lines)
+			if strings.Contains(strings.ToLower(lines[line]), query) {
+				m.viewport.SetYOffset(line)
+				return
+			}
+		}
+	}
+	m.message = fmt.Sprintf("No match for %q", m.query)
+}
+
+// ansiEscape matches the escape sequences that color the diffs, so that search ignores them.
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func stripANSI(s string) string {
+	return ansiEscape.ReplaceAllString(s, "")
+}
+
+func (m *model) copyYAML() {
+	o, exists := m.objects[m.selected]
+	if m.root != nil && m.root.GetUID() == m.selected {
+		o, exists = m.root, true
+	}
+	if !exists {
+		m.message = "No object selected"
+		return
+	}
+
+	y, err := yaml.Marshal(o.Object)
+	if err != nil {
+		m.message = fmt.Sprintf("Unable to serialize object: %v", err)
+		return
+	}
+	if err := clipboard.WriteAll(string(y)); err != nil {
+		m.message = fmt.Sprintf("Unable to copy to clipboard: %v", err)
+		return
+	}
+	m.message = fmt.Sprintf("Copied YAML of %s/%s to clipboard", o.GetKind(), o.GetName())
+}
+
+func (m *model) View() string {
+	var b strings.Builder
+
+	title := fmt.Sprintf("%s %s/%s", m.kind, m.namespace, m.name)
+	if m.screen != treeScreen && m.cursor < len(m.rows) {
+		node := m.rows[m.cursor].node
+		tab := "[changes]  status "
+		if m.screen == statusScreen {
+			tab = " changes  [status]"
+		}
+		title = fmt.Sprintf("%s/%s   %s", node.Kind, node.Name, tab)
+	}
+	b.WriteString(titleStyle.Render(title))
+	if m.paused {
+		b.WriteString(pausedStyle.Render(fmt.Sprintf("   PAUSED (%d events buffered)", len(m.pending))))
+	}
+	b.WriteString("\n\n")
+
+	if m.screen == treeScreen {
+		b.WriteString(m.treeView())
+	} else {
+		b.WriteString(m.viewport.View())
+	}
+	b.WriteString("\n")
+
+	switch {
+	case m.searching:
+		b.WriteString(m.search.View())
+	case m.message != "":
+		b.WriteString(m.message)
+	}
+	b.WriteString("\n")
+
+	help := "↑/↓ move • enter changes • s status • p pause • / search • n next • y copy YAML • q quit"
+	if m.screen != treeScreen {
+		help = "↑/↓ scroll • tab changes/status • esc back • p pause • / search • n next • y copy YAML • q quit"
+	}
+	b.WriteString(faintStyle.Render(help))
+
+	return b.String()
+}
+
+func (m *model) treeView() string {
+	if len(m.rows) == 0 {
+		return faintStyle.Render(fmt.Sprintf("Waiting for %s '%s/%s'", m.kind, m.namespace, m.name))
+	}
+
+	height := max(m.height-headerHeight-footerHeight, 1)
+	first := 0
+	if m.cursor >= height {
+		first = m.cursor - height + 1
+	}
+
+	nameWidth := 0
+	for _, r := range m.rows {
+		nameWidth = max(nameWidth, lipgloss.Width(r.prefix+r.node.Kind+"/"+r.node.Name))
+	}
+
+	lines := []string{}
+	for i := first; i < len(m.rows) && i < first+height; i++ {
+		r := m.rows[i]
+		name := r.prefix + r.node.Kind + "/" + r.node.Name
+		name += strings.Repeat(" ", nameWidth-lipgloss.Width(name))
+		if i == m.cursor {
+			name = selectedStyle.Render(name)
+		}
+
+		line := fmt.Sprintf("%s  %s  %s", name, readiness(r.node.Ready), faintStyle.Render(age(r.node.Created)))
+		if r.node.Reason != "" {
+			line += "  " + r.node.Reason
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func readiness(status string) string {
+	switch status {
+	case "True":
+		return readyStyle.Render("Ready   ")
+	case "False":
+		return notReadyStyle.Render("NotReady")
+	case "":
+		return faintStyle.Render("-       ")
+	default:
+		return unknownStyle.Render(fmt.Sprintf("%-8s", status))
+	}
+}
+
+func age(created time.Time) string {
+	if created.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(created))
+}
@@ -3,15 +3,19 @@ package watch
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
+	openapi_v2 "github.com/google/gnostic-models/openapiv2"
 	"github.com/pulumi/kubespy/k8sconfig"
 	"github.com/pulumi/kubespy/k8sobject"
 	"github.com/pulumi/pulumi-kubernetes/provider/v4/pkg/clients"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
@@ -29,11 +33,15 @@ var (
 	redBoldText  = color.New(color.FgRed, color.Bold)
 )
 
+// watchRetryInterval is how long to wait before trying again to restart a watch that ended.
+const watchRetryInterval = time.Second
+
 type watchType string
 
 const (
 	watchByName  watchType = "watchByName"
 	watchByOwner watchType = "watchByOwner"
+	watchByLabel watchType = "watchByLabel"
 	watchAll     watchType = "watchAll"
 )
 
@@ -52,6 +60,12 @@ func ObjectsOwnedBy(namespace, ownerName string) Opts {
 	return Opts{watchType: watchByOwner, namespace: namespace, ownerName: ownerName}
 }
 
+// ObjectsLabeled configures a watch to look for objects in `namespace` matching the label selector
+// `selector` (e.g., `kubernetes.io/service-name=nginx`).
+func ObjectsLabeled(namespace, selector string) Opts {
+	return Opts{watchType: watchByLabel, namespace: namespace, labelSelector: selector}
+}
+
 // Opts specifies which objects to watch for (e.g., "called this" or "owned by x").
 type Opts struct {
 	watchType watchType
@@ -65,6 +79,14 @@ type Opts struct {
 	// (Optional) ID of object that owns the object we're watching for (e.g., ReplicaSet owned by
 	// some Deployment).
 	ownerName string
+
+	// (Optional) label selector objects must match. Filtering happens server-side.
+	labelSelector string
+}
+
+// Namespace is the namespace the watch looks in, or "" for every namespace.
+func (opts *Opts) Namespace() string {
+	return opts.namespace
 }
 
 func (opts *Opts) Check(o *unstructured.Unstructured) bool {
@@ -76,7 +98,7 @@ func (opts *Opts) Check(o *unstructured.Unstructured) bool {
 			k8sobject.OwnedBy(o, "apps/v1beta1", "Deployment", opts.ownerName) ||
 			k8sobject.OwnedBy(o, "apps/v1beta1", "Deployment", opts.ownerName) ||
 			k8sobject.OwnedBy(o, "apps/v1", "Deployment", opts.ownerName)
-	case watchAll:
+	case watchByLabel, watchAll:
 		return true
 	default:
 		panic("Unknown watch type " + opts.watchType)
@@ -112,28 +134,221 @@ func Forever(apiVersion, kind string, opts Opts) (<-chan watch.Event, error) {
 		clientForResource = clientSet.GenericClient.Resource(mapping.Resource).Namespace(opts.namespace)
 	}
 
-	watcher, err := clientForResource.Watch(context.TODO(), metav1.ListOptions{})
+	list := func() (*unstructured.UnstructuredList, error) {
+		return clientForResource.List(context.TODO(), metav1.ListOptions{
+			LabelSelector: opts.labelSelector,
+		})
+	}
+	start := func(resourceVersion string) (watch.Interface, error) {
+		return clientForResource.Watch(context.TODO(), metav1.ListOptions{
+			LabelSelector: opts.labelSelector, ResourceVersion: resourceVersion,
+			AllowWatchBookmarks: true,
+		})
+	}
+	watcher, err := start("")
 	if err != nil {
 		return nil, err
 	}
 
 	out := make(chan watch.Event)
-	go func() {
+	go forward(watcher, list, start, opts, out)
+
+	return out, nil
+}
+
+// AllNamespacedKinds will watch every namespaced resource type the API server knows how to list and
+// watch, emitting `watch.Event` for objects in `namespace` until it is killed. Resource types the
+// user is not allowed to watch are skipped.
+func AllNamespacedKinds(namespace string) (<-chan watch.Event, error) {
+	clientSet, err := makeClientSet()
+	if err != nil {
+		return nil, err
+	}
+
+	// Discovery of some aggregated API groups (e.g., a broken metrics-server) routinely fails. We
+	// still want to show the resource types we were able to discover.
+	resourceLists, err := clientSet.DiscoveryClientCached.ServerPreferredNamespacedResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, err
+	}
+
+	resourceLists = discovery.FilteredBy(
+		discovery.SupportsAllVerbs{Verbs: []string{"list", "watch"}}, resourceLists)
+	gvrs, err := discovery.GroupVersionResources(resourceLists)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan watch.Event)
+	for gvr := range gvrs {
+		// Events are never owned by anything, and they are by far the noisiest resource type.
+		if gvr.Resource == "events" && (gvr.Group == "" || gvr.Group == "events.k8s.io") {
+			continue
+		}
+
+		client := clientSet.GenericClient.Resource(gvr).Namespace(namespace)
+		list := func() (*unstructured.UnstructuredList, error) {
+			return client.List(context.TODO(), metav1.ListOptions{})
+		}
+		start := func(resourceVersion string) (watch.Interface, error) {
+			return client.Watch(context.TODO(), metav1.ListOptions{
+				ResourceVersion: resourceVersion, AllowWatchBookmarks: true,
+			})
+		}
+		watcher, err := start("")
+		if err != nil {
+			continue
+		}
+		go forward(watcher, list, start, All(namespace), out)
+	}
+
+	return out, nil
+}
+
+// Events will watch the Kubernetes Events in `namespace` forever, emitting `watch.Event` until it is
+// killed. Events are watched through the `events.k8s.io/v1` API, or, on clusters that predate it
+// (Kubernetes v1.19), the `core/v1` one.
+func Events(namespace string) (<-chan watch.Event, error) {
+	events, err := Forever("events.k8s.io/v1", "Event", All(namespace))
+	if err != nil {
+		return Forever("v1", "Event", All(namespace))
+	}
+	return events, nil
+}
+
+// OpenAPISchema fetches the API server's OpenAPI (v2) document, which describes every kind it
+// serves.
+func OpenAPISchema() (*openapi_v2.Document, error) {
+	clientSet, err := makeClientSet()
+	if err != nil {
+		return nil, err
+	}
+	return clientSet.DiscoveryClientCached.OpenAPISchema()
+}
+
+// ResolveType maps a resource type as a user would type it to `kubectl` (e.g., `deploy`,
+// `deployments`, `Deployment`, or `deployments.apps`) to an apiVersion and kind.
+func ResolveType(resourceType string) (apiVersion, kind string, err error) {
+	clientSet, err := makeClientSet()
+	if err != nil {
+		return "", "", err
+	}
+
+	mapper := restmapper.NewShortcutExpander(
+		clientSet.RESTMapper, clientSet.DiscoveryClientCached, func(string) {})
+
+	fullySpecified, groupResource := schema.ParseResourceArg(strings.ToLower(resourceType))
+	gvr := groupResource.WithVersion("")
+	if fullySpecified != nil {
+		gvr = *fullySpecified
+	}
+
+	gvk, err := mapper.KindFor(gvr)
+	if err != nil {
+		return "", "", err
+	}
+
+	return gvk.GroupVersion().String(), gvk.Kind, nil
+}
+
+// forward emits the events of `watcher` for the objects `opts` selects to `out`, forever. The API
+// server closes watches after a while (typically 30 to 60 minutes), so whenever `watcher` ends, a
+// new watch is begun with `start`, from the last resourceVersion seen. If that resourceVersion is
+// too old to resume from, the objects are listed afresh with `list`, and compared with those emitted
+// so far (see relist), and the new watch begins from the list's resourceVersion.
+func forward(
+	watcher watch.Interface, list func() (*unstructured.UnstructuredList, error),
+	start func(resourceVersion string) (watch.Interface, error), opts Opts, out chan<- watch.Event,
+) {
+	// known is the latest state of every object emitted, and not since deleted.
+	known := map[types.UID]*unstructured.Unstructured{}
+	emit := func(e watch.Event) {
+		o := e.Object.(*unstructured.Unstructured)
+		if e.Type == watch.Deleted {
+			delete(known, o.GetUID())
+		} else {
+			known[o.GetUID()] = o
+		}
+		out <- e
+	}
+
+	resourceVersion := ""
+	for {
+		for e := range watcher.ResultChan() {
+			if e.Type == watch.Error {
+				// Most likely the resourceVersion has expired (`410 Gone`). The server ends the
+				// watch after sending this.
+				resourceVersion = ""
+				continue
+			}
+			o, isUnst := e.Object.(*unstructured.Unstructured)
+			if !isUnst {
+				continue
+			}
+			resourceVersion = o.GetResourceVersion()
+			if e.Type != watch.Bookmark && opts.Check(o) {
+				emit(e)
+			}
+		}
+		watcher.Stop()
+
 		for {
-			select {
-			case e := <-watcher.ResultChan():
-				o, isUnst := e.Object.(*unstructured.Unstructured)
-				if !isUnst {
+			var err error
+			if resourceVersion == "" {
+				resourceVersion, err = relist(list, opts, known, emit)
+			}
+			if err == nil {
+				if watcher, err = start(resourceVersion); err == nil {
 					break
 				}
-				if opts.Check(o) {
-					out <- e
-				}
 			}
+			resourceVersion = ""
+			time.Sleep(watchRetryInterval)
 		}
-	}()
+	}
+}
 
-	return out, nil
+// relist lists the objects `opts` selects with `list`, and emits what changed since `known`, the
+// objects emitted so far, were last seen: `ADDED` for objects that are new, `MODIFIED` for those
+// that have changed since, and `DELETED` for those that are gone. This is what a watch would have
+// emitted in the meantime, but for any intermediate states. It returns the list's resourceVersion,
+// from which to begin watching again.
+func relist(
+	list func() (*unstructured.UnstructuredList, error), opts Opts,
+	known map[types.UID]*unstructured.Unstructured, emit func(watch.Event),
+) (resourceVersion string, err error) {
+	objects, err := list()
+	if err != nil {
+		return "", err
+	}
+
+	listed := map[types.UID]bool{}
+	for i := range objects.Items {
+		o := &objects.Items[i]
+		if !opts.Check(o) {
+			continue
+		}
+		listed[o.GetUID()] = true
+		if last, exists := known[o.GetUID()]; !exists {
+			emit(watch.Event{Type: watch.Added, Object: o})
+		} else if last.GetResourceVersion() != o.GetResourceVersion() {
+			emit(watch.Event{Type: watch.Modified, Object: o})
+		}
+	}
+
+	// Emit the deletions in a stable order.
+	gone := []string{}
+	for uid := range known {
+		if !listed[uid] {
+			gone = append(gone, string(uid))
+		}
+	}
+	sort.Strings(gone)
+	for _, uid := range gone {
+		emit(watch.Event{Type: watch.Deleted, Object: known[types.UID(uid)]})
+	}
+
+	return objects.GetResourceVersion(), nil
 }
 
 func makeClientSet() (*clients.DynamicClientSet, error) {
@@ -0,0 +1,108 @@
+package watch
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func configMap(uid types.UID, resourceVersion string) *unstructured.Unstructured {
+	o := &unstructured.Unstructured{}
+	o.SetAPIVersion("v1")
+	o.SetKind("ConfigMap")
+	o.SetNamespace("default")
+	o.SetName(string(uid))
+	o.SetUID(uid)
+	o.SetResourceVersion(resourceVersion)
+	return o
+}
+
+// receive reads `n` events from `out`, rendering each as `TYPE uid@resourceVersion`.
+func receive(t *testing.T, out <-chan watch.Event, n int) []string {
+	t.Helper()
+	events := []string{}
+	for i := 0; i < n; i++ {
+		select {
+		case e := <-out:
+			o := e.Object.(*unstructured.Unstructured)
+			events = append(events,
+				fmt.Sprintf("%s %s@%s", e.Type, o.GetUID(), o.GetResourceVersion()))
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for an event, after %v", events)
+		}
+	}
+	return events
+}
+
+func TestForwardResumesWatch(t *testing.T) {
+	first := watch.NewFakeWithChanSize(2, false)
+	first.Add(configMap("a", "1"))
+	first.Modify(configMap("a", "2"))
+	first.Stop()
+
+	resumed := make(chan string, 1)
+	list := func() (*unstructured.UnstructuredList, error) {
+		t.Errorf("Expected the watch to be resumed without listing")
+		return nil, fmt.Errorf("unexpected list")
+	}
+	start := func(resourceVersion string) (watch.Interface, error) {
+		resumed <- resourceVersion
+		return watch.NewFake(), nil
+	}
+
+	out := make(chan watch.Event)
+	go forward(first, list, start, All("default"), out)
+
+	want := []string{"ADDED a@1", "MODIFIED a@2"}
+	if got := receive(t, out, len(want)); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := <-resumed; got != "2" {
+		t.Errorf("Expected the watch to resume from resourceVersion 2, got %q", got)
+	}
+}
+
+func TestForwardRelistsExpiredWatch(t *testing.T) {
+	first := watch.NewFakeWithChanSize(4, false)
+	first.Add(configMap("a", "1"))
+	first.Add(configMap("b", "1"))
+	first.Add(configMap("c", "1"))
+	first.Error(&metav1.Status{Code: 410, Reason: metav1.StatusReasonGone})
+	first.Stop()
+
+	list := func() (*unstructured.UnstructuredList, error) {
+		objects := &unstructured.UnstructuredList{}
+		objects.SetResourceVersion("10")
+		// `a` changed, `b` is gone, `c` is as it was, and `d` is new.
+		for _, o := range []*unstructured.Unstructured{
+			configMap("a", "5"), configMap("c", "1"), configMap("d", "7"),
+		} {
+			objects.Items = append(objects.Items, *o)
+		}
+		return objects, nil
+	}
+	resumed := make(chan string, 1)
+	start := func(resourceVersion string) (watch.Interface, error) {
+		resumed <- resourceVersion
+		return watch.NewFake(), nil
+	}
+
+	out := make(chan watch.Event)
+	go forward(first, list, start, All("default"), out)
+
+	want := []string{
+		"ADDED a@1", "ADDED b@1", "ADDED c@1",
+		"MODIFIED a@5", "ADDED d@7", "DELETED b@1",
+	}
+	if got := receive(t, out, len(want)); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := <-resumed; got != "10" {
+		t.Errorf("Expected the watch to begin from the list's resourceVersion 10, got %q", got)
+	}
+}